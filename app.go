@@ -101,14 +101,14 @@ func (app *Gosf) PanicErr(err error, v ...any) {
 
 // FmtLog 终端输出，日志也记录
 func (app *Gosf) FmtLog(v ...any) {
-	fmt.Println(v)
+	fmt.Println([]any(v))
 	app.Logger.Info(v)
 }
 
 // Exit 中断程序
 func (app *Gosf) Exit(v ...any) {
 	if len(v) > 0 {
-		fmt.Println(v)
+		fmt.Println([]any(v))
 		app.Logger.Fatal(v)
 	}
 	os.Exit(1)
@@ -0,0 +1,573 @@
+package dbtest
+
+/**
+内存中的 database/sql 驱动,用于对使用 DbPool 的代码做单元测试,不需要真实数据库
+按顺序登记预期的SQL与参数,返回预设的数据或错误,SQL比较时忽略多余的空白
+mock := dbtest.New()
+m := &gosf.Mysql{}
+_ = mock.Register(m, "base")
+mock.ExpectQuery("SELECT * FROM `user` WHERE id=? LIMIT 0, 10").
+	WithArgs(1).
+	WillReturnRows(dbtest.NewRows("id", "name").AddRow(1, "tom"))
+mock.ExpectExec("UPDATE `user` SET `name`=? WHERE id=?").
+	WithArgs("jerry", 1).
+	WillReturnResult(0, 1)
+mock.ExpectBegin()
+mock.ExpectExec("DELETE FROM `user` WHERE id=?").WithArgs(dbtest.AnyArg).WillReturnError(errors.New("locked"))
+mock.ExpectRollback()
+... 执行被测代码,例如 repo := NewUserRepo(m.MustDB("base"))
+if err := mock.ExpectationsWereMet(); err != nil {
+	t.Error(err)
+}
+Get、All 返回的 map 按列类型转换,与 MySQL 一致,类型默认按值推断,也可以指定
+mock.ExpectQuery("SELECT * FROM `goods` LIMIT 0, 10").
+	WillReturnRows(dbtest.NewRows("id", "price").WithColumnTypes("INT", "DECIMAL").AddRow(1, "9.90"))
+ExpectQueryMatch、ExpectExecMatch 以正则表达式匹配SQL,用于包含动态内容的语句
+未登记的SQL返回错误,同时记录在 ExpectationsWereMet 的结果中
+*/
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oyjz/gosf"
+)
+
+// AnyArg 匹配任意参数,用于时间等无法预知的值
+var AnyArg = anyArg{}
+
+type anyArg struct{}
+
+// 预期的语句类型
+const (
+	kindQuery    = "query"
+	kindExec     = "exec"
+	kindBegin    = "begin"
+	kindCommit   = "commit"
+	kindRollback = "rollback"
+)
+
+// Mock 模拟数据库,按登记顺序匹配语句,并发安全
+type Mock struct {
+	mu           sync.Mutex
+	db           *sql.DB
+	expectations []*Expectation
+	errs         []error
+}
+
+// New 创建模拟数据库
+func New() *Mock {
+	m := &Mock{}
+	m.db = sql.OpenDB(connector{mock: m})
+	// 只保留一个连接,事务与普通语句按顺序执行
+	m.db.SetMaxOpenConns(1)
+	return m
+}
+
+// DB 模拟数据库的连接池
+func (m *Mock) DB() *sql.DB {
+	return m.db
+}
+
+// Register 以 MySQL 方言注册为命名数据库
+func (m *Mock) Register(p *gosf.Mysql, name string) error {
+	return m.RegisterWith(p, name, gosf.MysqlDialect)
+}
+
+// RegisterWith 以指定方言注册为命名数据库
+func (m *Mock) RegisterWith(p *gosf.Mysql, name string, dialect gosf.Dialect) error {
+	return p.RegisterWith(name, dialect, m.db)
+}
+
+// ExpectQuery 登记预期的查询,sql 与实际SQL忽略多余空白后完全相同
+func (m *Mock) ExpectQuery(sql string) *Expectation {
+	return m.expect(kindQuery, sql, nil)
+}
+
+// ExpectQueryMatch 登记预期的查询,以正则表达式匹配SQL
+func (m *Mock) ExpectQueryMatch(pattern string) *Expectation {
+	return m.expect(kindQuery, "", regexp.MustCompile(pattern))
+}
+
+// ExpectExec 登记预期的写入语句
+func (m *Mock) ExpectExec(sql string) *Expectation {
+	return m.expect(kindExec, sql, nil)
+}
+
+// ExpectExecMatch 登记预期的写入语句,以正则表达式匹配SQL
+func (m *Mock) ExpectExecMatch(pattern string) *Expectation {
+	return m.expect(kindExec, "", regexp.MustCompile(pattern))
+}
+
+// ExpectBegin 登记预期的开启事务
+func (m *Mock) ExpectBegin() *Expectation {
+	return m.expect(kindBegin, "", nil)
+}
+
+// ExpectCommit 登记预期的提交事务
+func (m *Mock) ExpectCommit() *Expectation {
+	return m.expect(kindCommit, "", nil)
+}
+
+// ExpectRollback 登记预期的回滚事务
+func (m *Mock) ExpectRollback() *Expectation {
+	return m.expect(kindRollback, "", nil)
+}
+
+func (m *Mock) expect(kind, sql string, pattern *regexp.Regexp) *Expectation {
+	e := &Expectation{kind: kind, sql: normalize(sql), pattern: pattern}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// ExpectationsWereMet 检查全部预期是否按顺序执行,且没有未登记的语句
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var msgs []string
+	for _, err := range m.errs {
+		msgs = append(msgs, err.Error())
+	}
+	for _, e := range m.expectations {
+		if !e.triggered {
+			msgs = append(msgs, fmt.Sprintf("dbtest: expectation not met: %v", e))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(msgs, "\n"))
+}
+
+// next 取出下一条未执行的预期并检查是否匹配
+func (m *Mock) next(kind, query string, args []driver.NamedValue) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var err error
+	for _, e := range m.expectations {
+		if e.triggered {
+			continue
+		}
+		if err = e.match(kind, query, args); err == nil {
+			e.triggered = true
+			return e, nil
+		}
+		break
+	}
+	if err == nil {
+		err = fmt.Errorf("dbtest: unexpected %s %q with args %v", kind, query, namedValues(args))
+	}
+	m.errs = append(m.errs, err)
+	return nil, err
+}
+
+// Expectation 一条预期的语句
+type Expectation struct {
+	kind      string
+	sql       string
+	pattern   *regexp.Regexp
+	args      []interface{}
+	checkArgs bool
+	rows      *Rows
+	lastId    int64
+	affected  int64
+	err       error
+	delay     time.Duration
+	triggered bool
+}
+
+// WithArgs 预期的参数,按 database/sql 的规则转换后比较,AnyArg 匹配任意值
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	e.checkArgs = true
+	return e
+}
+
+// WillReturnRows 查询返回的数据
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnResult 写入语句返回的自增ID与影响行数
+func (e *Expectation) WillReturnResult(lastId, affected int64) *Expectation {
+	e.lastId = lastId
+	e.affected = affected
+	return e
+}
+
+// WillReturnError 执行时返回错误
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// WillDelayFor 执行前等待,ctx 先取消时返回 ctx 的错误
+func (e *Expectation) WillDelayFor(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+func (e *Expectation) String() string {
+	switch {
+	case e.pattern != nil:
+		return fmt.Sprintf("%s matching %q", e.kind, e.pattern.String())
+	case e.sql != "":
+		return fmt.Sprintf("%s %q", e.kind, e.sql)
+	}
+	return e.kind
+}
+
+// match 检查类型、SQL与参数
+func (e *Expectation) match(kind, query string, args []driver.NamedValue) error {
+	if e.kind != kind {
+		return fmt.Errorf("dbtest: got %s %q, want %v", kind, query, e)
+	}
+	if kind != kindQuery && kind != kindExec {
+		return nil
+	}
+	if e.pattern != nil {
+		if !e.pattern.MatchString(query) {
+			return fmt.Errorf("dbtest: %s %q does not match %q", kind, query, e.pattern.String())
+		}
+	} else if normalize(query) != e.sql {
+		return fmt.Errorf("dbtest: got %s %q, want %q", kind, normalize(query), e.sql)
+	}
+	if !e.checkArgs {
+		return nil
+	}
+	if len(args) != len(e.args) {
+		return fmt.Errorf("dbtest: %s %q got %d args, want %d", kind, query, len(args), len(e.args))
+	}
+	for i, want := range e.args {
+		if want == AnyArg {
+			continue
+		}
+		value, err := driver.DefaultParameterConverter.ConvertValue(want)
+		if err != nil {
+			return fmt.Errorf("dbtest: convert expected arg %d: %w", i, err)
+		}
+		if !equalValue(args[i].Value, value) {
+			return fmt.Errorf("dbtest: %s %q arg %d is %#v, want %#v", kind, query, i, args[i].Value, value)
+		}
+	}
+	return nil
+}
+
+// wait 按 WillDelayFor 等待
+func (e *Expectation) wait(ctx context.Context) error {
+	if e.delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(e.delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Rows 查询返回的数据
+type Rows struct {
+	columns []string
+	types   []string
+	values  [][]driver.Value
+	err     error
+}
+
+// NewRows 创建指定列的数据
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+// AddRow 添加一行,值的数量必须与列数相同,int 等类型按 database/sql 的规则转换
+func (r *Rows) AddRow(values ...interface{}) *Rows {
+	if len(values) != len(r.columns) {
+		panic(fmt.Sprintf("dbtest: row has %d values, want %d", len(values), len(r.columns)))
+	}
+	row := make([]driver.Value, len(values))
+	for i, v := range values {
+		value, err := driver.DefaultParameterConverter.ConvertValue(v)
+		if err != nil {
+			panic(fmt.Sprintf("dbtest: convert row value %d: %v", i, err))
+		}
+		row[i] = value
+	}
+	r.values = append(r.values, row)
+	return r
+}
+
+// WithColumnTypes 设置每列的数据库类型名称,如 INT、DECIMAL、VARCHAR,Get、All 按类型转换结果
+// 未设置时按第一行的值推断,整数为 BIGINT,浮点数为 DOUBLE,时间为 DATETIME,其余为 VARCHAR
+func (r *Rows) WithColumnTypes(types ...string) *Rows {
+	if len(types) != len(r.columns) {
+		panic(fmt.Sprintf("dbtest: got %d column types, want %d", len(types), len(r.columns)))
+	}
+	r.types = types
+	return r
+}
+
+// columnType 第 i 列的数据库类型名称
+func (r *Rows) columnType(i int) string {
+	if r.types != nil {
+		return r.types[i]
+	}
+	for _, row := range r.values {
+		switch row[i].(type) {
+		case nil:
+			continue
+		case int64:
+			return "BIGINT"
+		case float64:
+			return "DOUBLE"
+		case time.Time:
+			return "DATETIME"
+		}
+		return "VARCHAR"
+	}
+	return ""
+}
+
+// RowError 遍历完数据后返回的错误
+func (r *Rows) RowError(err error) *Rows {
+	r.err = err
+	return r
+}
+
+// normalize 合并连续空白
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func equalValue(got, want driver.Value) bool {
+	if t, ok := want.(time.Time); ok {
+		g, ok := got.(time.Time)
+		return ok && g.Equal(t)
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func namedValues(args []driver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// connector 返回模拟连接
+type connector struct {
+	mock *Mock
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return &conn{mock: c.mock}, nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+// fakeDriver 只用于满足 driver.Connector 接口
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("dbtest: use dbtest.New")
+}
+
+// conn 模拟连接
+type conn struct {
+	mock *Mock
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	e, err := c.mock.next(kindBegin, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.wait(ctx); err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &tx{conn: c}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.mock.next(kindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.wait(ctx); err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.rows == nil {
+		return &rows{}, nil
+	}
+	return &rows{source: e.rows}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.mock.next(kindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if err = e.wait(ctx); err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return result{lastId: e.lastId, affected: e.affected}, nil
+}
+
+// CheckNamedValue 参数交给 database/sql 默认规则转换
+func (c *conn) CheckNamedValue(v *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(v.Value)
+	if err != nil {
+		return err
+	}
+	v.Value = value
+	return nil
+}
+
+// stmt 预处理语句,执行时按普通语句匹配
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, toNamed(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, toNamed(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func toNamed(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+// tx 模拟事务
+type tx struct {
+	conn *conn
+}
+
+func (t *tx) Commit() error {
+	return t.finish(kindCommit)
+}
+
+func (t *tx) Rollback() error {
+	return t.finish(kindRollback)
+}
+
+func (t *tx) finish(kind string) error {
+	e, err := t.conn.mock.next(kind, "", nil)
+	if err != nil {
+		return err
+	}
+	return e.err
+}
+
+// result 写入结果
+type result struct {
+	lastId   int64
+	affected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastId, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
+
+// rows 查询结果游标
+type rows struct {
+	source *Rows
+	pos    int
+}
+
+func (r *rows) Columns() []string {
+	if r.source == nil {
+		return nil
+	}
+	return r.source.columns
+}
+
+// ColumnTypeDatabaseTypeName 实现 driver.RowsColumnTypeDatabaseTypeName
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if r.source == nil {
+		return ""
+	}
+	return r.source.columnType(index)
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.source == nil {
+		return io.EOF
+	}
+	if r.pos >= len(r.source.values) {
+		if r.source.err != nil {
+			return r.source.err
+		}
+		return io.EOF
+	}
+	copy(dest, r.source.values[r.pos])
+	r.pos++
+	return nil
+}
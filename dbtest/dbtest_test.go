@@ -0,0 +1,99 @@
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oyjz/gosf"
+)
+
+func TestMock(t *testing.T) {
+	mock := New()
+	m := &gosf.Mysql{}
+	if err := mock.Register(m, "base"); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	db := m.MustDB("base")
+
+	mock.ExpectQuery("SELECT * FROM `user` WHERE id=? LIMIT 0, 1").
+		WithArgs(1).
+		WillReturnRows(NewRows("id", "name").AddRow(1, "tom"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `user` SET `name`=? WHERE id=?").
+		WithArgs("jerry", AnyArg).
+		WillReturnResult(0, 1)
+	mock.ExpectExecMatch("^DELETE FROM").WillReturnError(errors.New("locked"))
+	mock.ExpectRollback()
+
+	var user struct {
+		Id   int64  `db:"id"`
+		Name string `db:"name"`
+	}
+	if err := db.Table("user").Where("id=?", 1).GetIntoCtx(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Id != 1 || user.Name != "tom" {
+		t.Fatalf("unexpected user %+v", user)
+	}
+	err := db.Transaction(context.Background(), func(tx *gosf.DbPool) error {
+		if _, err := tx.Table("user").Where("id=?", 1).Update(map[string]interface{}{"name": "jerry"}); err != nil {
+			return err
+		}
+		_, err := tx.Table("user").Where("id=?", 1).Delete()
+		return err
+	})
+	if err == nil || err.Error() != "locked" {
+		t.Fatalf("expected locked error, got %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMockUnexpected(t *testing.T) {
+	mock := New()
+	mock.ExpectExec("DELETE FROM user")
+	if _, err := mock.DB().Exec("UPDATE user SET name=?", "a"); err == nil {
+		t.Fatal("expected unexpected statement error")
+	}
+	if err := mock.ExpectationsWereMet(); err == nil {
+		t.Fatal("expected unmet expectations")
+	}
+}
+
+func TestMockColumnTypes(t *testing.T) {
+	mock := New()
+	m := &gosf.Mysql{}
+	if err := mock.Register(m, "base"); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	db := m.MustDB("base")
+
+	mock.ExpectQuery("SELECT * FROM `user` WHERE id=? LIMIT 0, 1").
+		WithArgs(1).
+		WillReturnRows(NewRows("id", "name", "score").AddRow(1, "tom", nil))
+	mock.ExpectQuery("SELECT * FROM `goods` LIMIT 0, 10").
+		WillReturnRows(NewRows("id", "price", "code").WithColumnTypes("INT UNSIGNED", "DECIMAL", "VARCHAR").AddRow(2, "9.90", 7))
+
+	// 未指定类型时整数按 BIGINT 转换为 int
+	user, err := db.Table("user").Where("id=?", 1).GetCtx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user["id"] != 1 || user["name"] != "tom" || user["score"] != nil {
+		t.Errorf("unexpected user %#v", user)
+	}
+	goods, err := db.Table("goods").AllCtx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(goods) != 1 || goods[0]["id"] != 2 || goods[0]["price"] != 9.9 || goods[0]["code"] != "7" {
+		t.Errorf("unexpected goods %#v", goods)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -66,7 +66,7 @@ func MkdirAll(path string) {
 // Exit 退出程序
 func Exit(v ...any) {
 	if len(v) > 0 {
-		log.Println(v)
+		log.Println([]any(v))
 	}
 	os.Exit(1)
 }
@@ -0,0 +1,467 @@
+package gen
+
+/**
+根据 MySQL 表结构生成模型结构体与仓储代码,每个表生成一个文件
+m := gen.New(mysql.MustDB("base")).Package("model")
+tables, err := m.Inspect(ctx, "user", "order") // 不指定表名时读取当前库全部表
+err = m.Write(ctx, "./model", "user", "order")
+命令行,基于 gosf 的 mysql 配置打开数据库
+err := gen.Command(ctx, app.Config.Data, os.Args[2:]) // -db base -pkg model -out ./model -tables user,order
+生成的代码
+type User struct {
+	Id   uint64 `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+}
+const UserTable = "user" / UserColumnId = "id" / UserColumnName = "name"
+repo := model.NewUserRepo(db)
+user, err := repo.FindByID(ctx, 1)
+err = repo.Create(&user)             // 自增主键写回 user.Id
+n, err := repo.Update(&user)
+n, err := repo.Delete(1)
+*/
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/oyjz/gosf"
+	"github.com/oyjz/gosf/config"
+)
+
+// Table 表结构
+type Table struct {
+	Name       string
+	Comment    string
+	Columns    []Column
+	PrimaryKey []string // 主键列,按定义顺序
+}
+
+// Column 列结构
+type Column struct {
+	Name          string
+	DataType      string // 数据类型,如 int、varchar
+	ColumnType    string // 完整类型,如 int(10) unsigned
+	Comment       string
+	Nullable      bool
+	PrimaryKey    bool
+	AutoIncrement bool
+}
+
+// Unsigned 是否为无符号数字
+func (c Column) Unsigned() bool {
+	return strings.Contains(strings.ToLower(c.ColumnType), "unsigned")
+}
+
+// Generator 代码生成器
+type Generator struct {
+	db  *gosf.DbPool
+	pkg string
+}
+
+// New 创建代码生成器,默认包名为 model
+func New(db *gosf.DbPool) *Generator {
+	return &Generator{db: db, pkg: "model"}
+}
+
+// Package 设置生成代码的包名
+func (g *Generator) Package(name string) *Generator {
+	g.pkg = name
+	return g
+}
+
+// columnRow information_schema.COLUMNS 查询结果
+type columnRow struct {
+	Table      string `db:"table_name"`
+	Name       string `db:"column_name"`
+	DataType   string `db:"data_type"`
+	ColumnType string `db:"column_type"`
+	Nullable   string `db:"is_nullable"`
+	Key        string `db:"column_key"`
+	Extra      string `db:"extra"`
+	Comment    string `db:"column_comment"`
+}
+
+// tableRow information_schema.TABLES 查询结果
+type tableRow struct {
+	Name    string `db:"table_name"`
+	Comment string `db:"table_comment"`
+}
+
+// Inspect 读取当前数据库的表结构,不指定表名时读取全部表,按表名排序
+func (g *Generator) Inspect(ctx context.Context, tables ...string) ([]Table, error) {
+	tableQuery := g.db.Table("information_schema.TABLES").
+		Select("TABLE_NAME AS table_name", "TABLE_COMMENT AS table_comment").
+		Where("TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'").
+		OrderBy("TABLE_NAME").
+		Limit(0)
+	columnQuery := g.db.Table("information_schema.COLUMNS").
+		Select("TABLE_NAME AS table_name", "COLUMN_NAME AS column_name", "DATA_TYPE AS data_type",
+			"COLUMN_TYPE AS column_type", "IS_NULLABLE AS is_nullable", "COLUMN_KEY AS column_key",
+			"EXTRA AS extra", "COLUMN_COMMENT AS column_comment").
+		Where("TABLE_SCHEMA = DATABASE()").
+		OrderBy("TABLE_NAME", "ORDINAL_POSITION").
+		Limit(0)
+	if len(tables) > 0 {
+		tableQuery = tableQuery.Where(gosf.In("TABLE_NAME", tables))
+		columnQuery = columnQuery.Where(gosf.In("TABLE_NAME", tables))
+	}
+	var tableRows []tableRow
+	if err := tableQuery.AllIntoCtx(ctx, &tableRows); err != nil {
+		return nil, fmt.Errorf("gen: read tables: %w", err)
+	}
+	var columnRows []columnRow
+	if err := columnQuery.AllIntoCtx(ctx, &columnRows); err != nil {
+		return nil, fmt.Errorf("gen: read columns: %w", err)
+	}
+
+	result := make([]Table, 0, len(tableRows))
+	index := make(map[string]int, len(tableRows))
+	for _, row := range tableRows {
+		index[row.Name] = len(result)
+		result = append(result, Table{Name: row.Name, Comment: row.Comment})
+	}
+	for _, row := range columnRows {
+		i, ok := index[row.Table]
+		if !ok {
+			continue
+		}
+		column := Column{
+			Name:          row.Name,
+			DataType:      strings.ToLower(row.DataType),
+			ColumnType:    strings.ToLower(row.ColumnType),
+			Comment:       row.Comment,
+			Nullable:      row.Nullable == "YES",
+			PrimaryKey:    row.Key == "PRI",
+			AutoIncrement: strings.Contains(strings.ToLower(row.Extra), "auto_increment"),
+		}
+		result[i].Columns = append(result[i].Columns, column)
+		if column.PrimaryKey {
+			result[i].PrimaryKey = append(result[i].PrimaryKey, column.Name)
+		}
+	}
+	for _, name := range tables {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("gen: table %q not found", name)
+		}
+	}
+	return result, nil
+}
+
+// Write 读取表结构并生成代码到 dir 目录,每个表一个文件,文件名为表名
+func (g *Generator) Write(ctx context.Context, dir string, tables ...string) error {
+	list, err := g.Inspect(ctx, tables...)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for _, table := range list {
+		code, err := Generate(g.pkg, table)
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(dir, strings.ToLower(table.Name)+".go")
+		if err = os.WriteFile(file, code, 0644); err != nil {
+			return err
+		}
+		fmt.Println("gen:", file)
+	}
+	return nil
+}
+
+// Command 执行命令行形式的生成命令:-db base -pkg model -out ./model -tables user,order
+// 数据库使用 gosf 的 mysql 配置打开,执行完毕后关闭连接
+func Command(ctx context.Context, cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	dbName := flags.String("db", "", "mysql 配置中的数据库名称")
+	pkg := flags.String("pkg", "model", "生成代码的包名")
+	out := flags.String("out", "./model", "输出目录")
+	tables := flags.String("tables", "", "表名,多个以逗号分隔,为空时生成全部表")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dbName == "" {
+		return errors.New("gen: -db is required")
+	}
+	m, err := gosf.OpenMysql(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	db, err := m.DB(*dbName)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, name := range strings.Split(*tables, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return New(db).Package(*pkg).Write(ctx, *out, names...)
+}
+
+// field 模板中的字段
+type field struct {
+	Column
+	Field string // 字段名
+	Type  string // Go 类型
+	Omit  string // 非空判断,为空时 Values 不包含该列,如自增主键与未设置的创建/更新时间
+}
+
+// model 模板数据
+type model struct {
+	Package string
+	Table   Table
+	Name    string
+	Fields  []field
+	Imports []string // 标准库
+	Key     *field   // 单列主键,联合主键或无主键时为nil
+}
+
+// Generate 生成单个表的代码,已使用 gofmt 格式化
+func Generate(pkg string, table Table) ([]byte, error) {
+	if len(table.Columns) == 0 {
+		return nil, fmt.Errorf("gen: table %q has no columns", table.Name)
+	}
+	data := model{Package: pkg, Table: table, Name: exportName(table.Name)}
+	imports := map[string]bool{}
+	for _, column := range table.Columns {
+		goType, pkgPath := goType(column)
+		if pkgPath != "" {
+			imports[pkgPath] = true
+		}
+		f := field{Column: column, Field: exportName(column.Name), Type: goType}
+		f.Omit = omitCond(f)
+		data.Fields = append(data.Fields, f)
+	}
+	if len(table.PrimaryKey) == 1 {
+		for i := range data.Fields {
+			if data.Fields[i].Name == table.PrimaryKey[0] {
+				data.Key = &data.Fields[i]
+				imports["context"] = true
+			}
+		}
+	}
+	for path := range imports {
+		data.Imports = append(data.Imports, path)
+	}
+	sort.Strings(data.Imports)
+
+	var buf bytes.Buffer
+	if err := modelTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: format %s: %w", table.Name, err)
+	}
+	return code, nil
+}
+
+// omitCond 零值时不写入的列的非空判断:自增主键由数据库生成,
+// created_at/updated_at 未设置时交由数据库默认值或 TableOptions.Timestamps 填充
+func omitCond(f field) string {
+	if f.AutoIncrement {
+		return "m." + f.Field + " != 0"
+	}
+	if f.Name != "created_at" && f.Name != "updated_at" {
+		return ""
+	}
+	switch f.Type {
+	case "time.Time":
+		return "!m." + f.Field + ".IsZero()"
+	case "*time.Time":
+		return "m." + f.Field + " != nil"
+	}
+	return ""
+}
+
+// goType 列类型对应的 Go 类型与需要导入的包,可为NULL的列使用指针
+func goType(c Column) (string, string) {
+	var t, pkgPath string
+	unsigned := c.Unsigned()
+	switch c.DataType {
+	case "tinyint":
+		t = "int8"
+		if unsigned {
+			t = "uint8"
+		}
+	case "smallint":
+		t = "int16"
+		if unsigned {
+			t = "uint16"
+		}
+	case "mediumint", "int", "integer":
+		t = "int32"
+		if unsigned {
+			t = "uint32"
+		}
+	case "bigint":
+		t = "int64"
+		if unsigned {
+			t = "uint64"
+		}
+	case "float":
+		t = "float32"
+	case "double", "real", "decimal", "numeric":
+		t = "float64"
+	case "date", "datetime", "timestamp":
+		t, pkgPath = "time.Time", "time"
+	case "json":
+		t, pkgPath = "json.RawMessage", "encoding/json"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit":
+		t = "[]byte"
+	default:
+		t = "string"
+	}
+	if c.Nullable && !strings.HasPrefix(t, "[]") && t != "json.RawMessage" {
+		t = "*" + t
+	}
+	return t, pkgPath
+}
+
+// exportName 下划线命名转为大驼峰,如 user_id 转为 UserId
+func exportName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' || r == ' ' || r == '.' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit([]rune(s)[0]) {
+		s = "T" + s
+	}
+	return s
+}
+
+// oneLine 注释去除换行
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var modelTemplate = template.Must(template.New("model").Funcs(template.FuncMap{"oneLine": oneLine}).Parse(`// Code generated by gosf gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+{{if .Imports}}
+{{end -}}
+	"github.com/oyjz/gosf"
+)
+
+// {{.Name}}Table 表名
+const {{.Name}}Table = "{{.Table.Name}}"
+
+// {{.Name}} 列名
+const (
+{{- range .Fields}}
+	{{$.Name}}Column{{.Field}} = "{{.Name}}"{{if .Comment}} // {{oneLine .Comment}}{{end}}
+{{- end}}
+)
+
+// {{.Name}}{{if .Table.Comment}} {{oneLine .Table.Comment}}{{else}} {{.Table.Name}} 表{{end}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Field}} {{.Type}} ` + "`" + `db:"{{.Name}}" json:"{{.Name}}"` + "`" + `{{if .Comment}} // {{oneLine .Comment}}{{end}}
+{{- end}}
+}
+
+// TableName 表名
+func ({{.Name}}) TableName() string {
+	return {{.Name}}Table
+}
+
+// PrimaryKey 主键列
+func ({{.Name}}) PrimaryKey() []string {
+	return []string{ {{- range $i, $k := .Table.PrimaryKey}}{{if $i}}, {{end}}"{{$k}}"{{end -}} }
+}
+
+// Values 全部列的值,自增主键与创建/更新时间为零值时不包含
+func (m *{{.Name}}) Values() map[string]interface{} {
+	values := map[string]interface{}{
+{{- range .Fields}}{{if not .Omit}}
+		{{$.Name}}Column{{.Field}}: m.{{.Field}},
+{{- end}}{{end}}
+	}
+{{- range .Fields}}{{if .Omit}}
+	if {{.Omit}} {
+		values[{{$.Name}}Column{{.Field}}] = m.{{.Field}}
+	}
+{{- end}}{{end}}
+	return values
+}
+
+// {{.Name}}Repo {{.Table.Name}} 表的数据访问
+type {{.Name}}Repo struct {
+	db *gosf.DbPool
+}
+
+// New{{.Name}}Repo 创建 {{.Table.Name}} 表的数据访问
+func New{{.Name}}Repo(db *gosf.DbPool) *{{.Name}}Repo {
+	return &{{.Name}}Repo{db: db}
+}
+
+// Query 基于 {{.Table.Name}} 表的查询构造器
+func (r *{{.Name}}Repo) Query() *gosf.DbPool {
+	return r.db.Table({{.Name}}Table)
+}
+
+// Create 插入数据{{if .Key}}{{if .Key.AutoIncrement}},自增主键写回 m.{{.Key.Field}}{{end}}{{end}}
+func (r *{{.Name}}Repo) Create(m *{{.Name}}) error {
+	{{if and .Key .Key.AutoIncrement}}id{{else}}_{{end}}, err := r.Query().Insert(m.Values())
+	if err != nil {
+		return err
+	}
+{{- if and .Key .Key.AutoIncrement}}
+	if id > 0 {
+		m.{{.Key.Field}} = {{.Key.Type}}(id)
+	}
+{{- end}}
+	return nil
+}
+{{- with .Key}}
+
+// FindByID 按主键查询,未查到数据时返回 gosf.ErrNoRows
+func (r *{{$.Name}}Repo) FindByID(ctx context.Context, id {{.Type}}) (*{{$.Name}}, error) {
+	var m {{$.Name}}
+	if err := r.Query().Where(gosf.Eq({{$.Name}}Column{{.Field}}, id)).GetIntoCtx(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Update 按主键更新除主键外的全部列,返回影响的行数
+func (r *{{$.Name}}Repo) Update(m *{{$.Name}}) (int, error) {
+	values := m.Values()
+	delete(values, {{$.Name}}Column{{.Field}})
+	return r.Query().Where(gosf.Eq({{$.Name}}Column{{.Field}}, m.{{.Field}})).Update(values)
+}
+
+// Delete 按主键删除,返回影响的行数
+func (r *{{$.Name}}Repo) Delete(id {{.Type}}) (int, error) {
+	return r.Query().Where(gosf.Eq({{$.Name}}Column{{.Field}}, id)).Delete()
+}
+{{- end}}
+`))
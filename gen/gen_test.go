@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	table := Table{
+		Name:    "user_account",
+		Comment: "用户账号",
+		Columns: []Column{
+			{Name: "id", DataType: "bigint", ColumnType: "bigint(20) unsigned", PrimaryKey: true, AutoIncrement: true},
+			{Name: "nick_name", DataType: "varchar", ColumnType: "varchar(32)", Comment: "昵称"},
+			{Name: "email", DataType: "varchar", ColumnType: "varchar(64)", Nullable: true},
+			{Name: "profile", DataType: "json", ColumnType: "json", Nullable: true},
+			{Name: "created_at", DataType: "datetime", ColumnType: "datetime"},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	code, err := Generate("model", table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`const UserAccountTable = "user_account"`,
+		`UserAccountColumnNickName  = "nick_name" // 昵称`,
+		"Email     *string         `db:\"email\" json:\"email\"`",
+		"Profile   json.RawMessage `db:\"profile\" json:\"profile\"`",
+		`func (r *UserAccountRepo) FindByID(ctx context.Context, id uint64) (*UserAccount, error) {`,
+		`m.Id = uint64(id)`,
+		`return []string{"id"}`,
+	} {
+		if !strings.Contains(string(code), want) {
+			t.Errorf("generated code missing %q\n%s", want, code)
+		}
+	}
+}
+
+func TestExportName(t *testing.T) {
+	for name, want := range map[string]string{"user_id": "UserId", "order": "Order", "2fa_code": "T2faCode"} {
+		if got := exportName(name); got != want {
+			t.Errorf("exportName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGenerateGolden(t *testing.T) {
+	table := Table{
+		Name:    "order",
+		Comment: "订单",
+		Columns: []Column{
+			{Name: "id", DataType: "int", ColumnType: "int(10) unsigned", PrimaryKey: true, AutoIncrement: true},
+			{Name: "amount", DataType: "decimal", ColumnType: "decimal(10,2)", Comment: "金额"},
+			{Name: "paid_at", DataType: "datetime", ColumnType: "datetime", Nullable: true},
+			{Name: "created_at", DataType: "datetime", ColumnType: "datetime"},
+			{Name: "updated_at", DataType: "timestamp", ColumnType: "timestamp", Nullable: true},
+		},
+		PrimaryKey: []string{"id"},
+	}
+	code, err := Generate("model", table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "order.go.golden")
+	if *update {
+		if err = os.WriteFile(golden, code, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, want) {
+		t.Errorf("generated code differs from %s, run go test -update\n%s", golden, code)
+	}
+}
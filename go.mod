@@ -3,7 +3,9 @@ module github.com/oyjz/gosf
 go 1.19
 
 require (
-	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/go-sql-driver/mysql v1.8.1 // indirect
-	github.com/patrickmn/go-cache v2.1.0+incompatible // indirect
+	github.com/go-sql-driver/mysql v1.8.1
+	github.com/mattn/go-sqlite3 v1.14.22
+	github.com/patrickmn/go-cache v2.1.0+incompatible
 )
+
+require filippo.io/edwards25519 v1.1.0 // indirect
@@ -70,25 +70,25 @@ func (logger *Logger) Debug(v ...any) {
 	if len(logger.Path) > 0 {
 		logger.DebugLogger.SetOutput(logger.GetLogFile("debug"))
 	}
-	logger.DebugLogger.Println(v)
+	logger.DebugLogger.Println([]any(v))
 }
 func (logger *Logger) Info(v ...any) {
 	if len(logger.Path) > 0 {
 		logger.InfoLogger.SetOutput(logger.GetLogFile("info"))
 	}
-	logger.InfoLogger.Println(v)
+	logger.InfoLogger.Println([]any(v))
 }
 func (logger *Logger) Error(v ...any) {
 	if len(logger.Path) > 0 {
 		logger.ErrorLogger.SetOutput(logger.GetLogFile("error"))
 	}
-	logger.ErrorLogger.Println(v)
+	logger.ErrorLogger.Println([]any(v))
 }
 func (logger *Logger) Fatal(v ...any) {
 	if len(logger.Path) > 0 {
 		logger.FatalLogger.SetOutput(logger.GetLogFile("fatal"))
 	}
-	logger.FatalLogger.Fatalln(v)
+	logger.FatalLogger.Fatalln([]any(v))
 }
 
 func (logger *Logger) GetLogFile(level string) *os.File {
@@ -0,0 +1,516 @@
+package migrate
+
+/**
+数据库迁移
+迁移文件命名为 {版本号}_{名称}.up.sql / {版本号}_{名称}.down.sql,例如
+	0001_create_user.up.sql
+	0001_create_user.down.sql
+	0002_add_user_email.up.sql
+文件中多条语句以 ; 分隔,不支持 DELIMITER
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+source, _ := fs.Sub(migrations, "migrations")
+m := migrate.New(mysql.MustDB("base"), source)
+err := m.Up(ctx)                  // 执行全部未执行的迁移
+err := m.Down(ctx, 1)             // 回滚最近1个版本
+list, err := m.Status(ctx)        // 查看迁移状态
+err := m.Force(ctx, 2)            // 强制设置版本,不执行SQL
+err := m.Command(ctx, os.Args[2:]) // 命令行:up | down N | status | force V
+启动时执行
+err := migrate.Run(ctx, mysql.MustDB("base"), migrate.Dir("./migrations"))
+*/
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/oyjz/gosf"
+)
+
+// 默认参数
+const (
+	DefaultTable       = "schema_migrations"
+	DefaultLockName    = "gosf_migrate"
+	DefaultLockTimeout = 60 * time.Second
+)
+
+// 版本记录时间格式
+const timeLayout = "2006-01-02 15:04:05"
+
+// ErrDirty 存在执行失败的迁移,需要修复后使用 Force 设置版本
+var ErrDirty = errors.New("migrate: database is dirty")
+
+// 迁移文件名格式
+var fileRegexp = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration 单个版本的迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string // 升级SQL
+	Down    string // 回滚SQL
+	HasDown bool
+}
+
+// Status 迁移状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db          *gosf.DbPool
+	source      fs.FS
+	table       string
+	lockName    string
+	lockTimeout time.Duration
+}
+
+// New 创建迁移执行器,source 可以是 embed.FS 或 Dir 返回的目录
+func New(db *gosf.DbPool, source fs.FS) *Migrator {
+	return &Migrator{
+		db:          db,
+		source:      source,
+		table:       DefaultTable,
+		lockName:    DefaultLockName,
+		lockTimeout: DefaultLockTimeout,
+	}
+}
+
+// Dir 使用本地目录作为迁移文件来源
+func Dir(dir string) fs.FS {
+	return os.DirFS(dir)
+}
+
+// Run 执行全部未执行的迁移,用于程序启动时调用
+func Run(ctx context.Context, db *gosf.DbPool, source fs.FS) error {
+	return New(db, source).Up(ctx)
+}
+
+// Table 设置记录迁移版本的数据表,默认 schema_migrations
+func (m *Migrator) Table(name string) *Migrator {
+	m.table = name
+	return m
+}
+
+// Lock 设置迁移锁名称与等待时间,同一数据库多个实例同时启动时只有一个执行迁移
+func (m *Migrator) Lock(name string, timeout time.Duration) *Migrator {
+	m.lockName = name
+	m.lockTimeout = timeout
+	return m
+}
+
+// Migrations 读取并按版本排序全部迁移文件
+func (m *Migrator) Migrations() ([]Migration, error) {
+	return load(m.source)
+}
+
+// Up 执行全部未执行的迁移
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		migrations, err := load(m.source)
+		if err != nil {
+			return err
+		}
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err = checkDirty(applied); err != nil {
+			return err
+		}
+		for _, item := range migrations {
+			if _, ok := applied[item.Version]; ok {
+				continue
+			}
+			if err = m.apply(ctx, conn, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Down 回滚最近 n 个已执行的版本
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("migrate: invalid down steps %d", n)
+	}
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		migrations, err := load(m.source)
+		if err != nil {
+			return err
+		}
+		byVersion := make(map[int64]Migration, len(migrations))
+		for _, item := range migrations {
+			byVersion[item.Version] = item
+		}
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err = checkDirty(applied); err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if n > len(versions) {
+			n = len(versions)
+		}
+		for _, version := range versions[:n] {
+			item, ok := byVersion[version]
+			if !ok || !item.HasDown {
+				return fmt.Errorf("migrate: no down migration for version %d", version)
+			}
+			if err = m.revert(ctx, conn, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Status 获取全部迁移状态,包含已记录但迁移文件已不存在的版本
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := load(m.source)
+	if err != nil {
+		return nil, err
+	}
+	// 持有迁移锁读取,避免与执行中的迁移并发创建版本表或读到中间状态
+	var applied map[int64]Status
+	err = m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err = m.applied(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, 0, len(migrations))
+	for _, item := range migrations {
+		status := Status{Version: item.Version, Name: item.Name}
+		if record, ok := applied[item.Version]; ok {
+			status.Applied = true
+			status.Dirty = record.Dirty
+			status.AppliedAt = record.AppliedAt
+			delete(applied, item.Version)
+		}
+		list = append(list, status)
+	}
+	for _, record := range applied {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Force 强制设置当前版本,不执行SQL:小于等于 version 的版本记为已执行,大于的版本记录被删除,并清除失败标记
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		migrations, err := load(m.source)
+		if err != nil {
+			return err
+		}
+		if _, err = conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM `%s` WHERE version > ?", m.table), version); err != nil {
+			return err
+		}
+		if _, err = conn.ExecContext(ctx, fmt.Sprintf("UPDATE `%s` SET dirty = 0", m.table)); err != nil {
+			return err
+		}
+		for _, item := range migrations {
+			if item.Version > version {
+				break
+			}
+			_, err = conn.ExecContext(ctx, fmt.Sprintf("INSERT IGNORE INTO `%s` (version, name, dirty, applied_at) VALUES (?, ?, 0, ?)", m.table),
+				item.Version, item.Name, time.Now().Format(timeLayout))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Command 执行命令行形式的迁移命令:up | down N | status | force V
+func (m *Migrator) Command(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate: usage: up | down N | status | force V")
+	}
+	switch args[0] {
+	case "up":
+		return m.Up(ctx)
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("migrate: invalid down steps %q", args[1])
+			}
+		}
+		return m.Down(ctx, n)
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range list {
+			state := "pending"
+			if status.Dirty {
+				state = "dirty"
+			} else if status.Applied {
+				state = "applied " + status.AppliedAt.Format(timeLayout)
+			}
+			fmt.Printf("%d\t%s\t%s\n", status.Version, status.Name, state)
+		}
+		return nil
+	case "force":
+		if len(args) < 2 {
+			return errors.New("migrate: force requires a version")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("migrate: invalid version %q", args[1])
+		}
+		return m.Force(ctx, version)
+	}
+	return fmt.Errorf("migrate: unknown command %q", args[0])
+}
+
+// withLock 获取专用连接与 GET_LOCK 迁移锁后执行 fn,迁移SQL均在该连接上执行
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) (err error) {
+	conn, err := m.db.GetPool().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.lockName, int(m.lockTimeout.Seconds())).Scan(&locked)
+	if err != nil {
+		return fmt.Errorf("migrate: get lock: %w", err)
+	}
+	if locked.Int64 != 1 {
+		return fmt.Errorf("migrate: timeout waiting for lock %q", m.lockName)
+	}
+	defer func() {
+		// 使用独立的上下文释放锁,避免 ctx 已取消时锁无法释放
+		_, _ = conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.lockName)
+	}()
+
+	if err = m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// ensureTable 创建迁移版本记录表
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` ("+
+		"version BIGINT NOT NULL PRIMARY KEY,"+
+		"name VARCHAR(255) NOT NULL DEFAULT '',"+
+		"dirty TINYINT(1) NOT NULL DEFAULT 0,"+
+		"applied_at DATETIME NOT NULL"+
+		")", m.table))
+	return err
+}
+
+// applied 读取已记录的版本
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]Status, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version, name, dirty, applied_at FROM `%s`", m.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int64]Status)
+	for rows.Next() {
+		var status Status
+		var appliedAt interface{}
+		if err = rows.Scan(&status.Version, &status.Name, &status.Dirty, &appliedAt); err != nil {
+			return nil, err
+		}
+		status.Applied = true
+		// DSN 开启 parseTime 时驱动返回 time.Time,否则为字符串
+		switch v := appliedAt.(type) {
+		case time.Time:
+			status.AppliedAt = v
+		case []byte:
+			status.AppliedAt, _ = time.Parse(timeLayout, string(v))
+		}
+		applied[status.Version] = status
+	}
+	return applied, rows.Err()
+}
+
+// apply 执行单个版本的升级SQL,执行前记为失败状态,全部成功后清除
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, item Migration) error {
+	fmt.Println("migrate up", item.Version, item.Name)
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("INSERT INTO `%s` (version, name, dirty, applied_at) VALUES (?, ?, 1, ?)", m.table),
+		item.Version, item.Name, time.Now().Format(timeLayout))
+	if err != nil {
+		return err
+	}
+	if err = execStatements(ctx, conn, item.Up); err != nil {
+		return fmt.Errorf("migrate: version %d %s failed, database is dirty: %w", item.Version, item.Name, err)
+	}
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("UPDATE `%s` SET dirty = 0 WHERE version = ?", m.table), item.Version)
+	return err
+}
+
+// revert 执行单个版本的回滚SQL,成功后删除版本记录
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, item Migration) error {
+	fmt.Println("migrate down", item.Version, item.Name)
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("UPDATE `%s` SET dirty = 1 WHERE version = ?", m.table), item.Version)
+	if err != nil {
+		return err
+	}
+	if err = execStatements(ctx, conn, item.Down); err != nil {
+		return fmt.Errorf("migrate: revert version %d %s failed, database is dirty: %w", item.Version, item.Name, err)
+	}
+	_, err = conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM `%s` WHERE version = ?", m.table), item.Version)
+	return err
+}
+
+// checkDirty 存在失败的版本时拒绝继续执行
+func checkDirty(applied map[int64]Status) error {
+	for version, status := range applied {
+		if status.Dirty {
+			return fmt.Errorf("%w: version %d, fix it manually and use force", ErrDirty, version)
+		}
+	}
+	return nil
+}
+
+// execStatements 逐条执行SQL
+func execStatements(ctx context.Context, conn *sql.Conn, text string) error {
+	for _, statement := range splitStatements(text) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load 读取迁移文件并按版本排序
+func load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: read source: %w", err)
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		item, ok := byVersion[version]
+		if !ok {
+			item = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = item
+		} else if item.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d has conflicting names %q and %q", version, item.Name, match[2])
+		}
+		if match[3] == "up" {
+			item.Up = string(content)
+		} else {
+			item.Down = string(content)
+			item.HasDown = true
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, item := range byVersion {
+		if strings.TrimSpace(item.Up) == "" {
+			return nil, fmt.Errorf("migrate: version %d %s has no up migration", item.Version, item.Name)
+		}
+		migrations = append(migrations, *item)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements 按 ; 拆分SQL,忽略引号与注释中的分号,去除空语句
+func splitStatements(text string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		statement := strings.TrimSpace(current.String())
+		if statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			// 引号内原样保留
+			current.WriteRune(r)
+			for i++; i < len(runes); i++ {
+				current.WriteRune(runes[i])
+				if runes[i] == '\\' && r != '`' && i+1 < len(runes) {
+					i++
+					current.WriteRune(runes[i])
+				} else if runes[i] == r {
+					break
+				}
+			}
+		case r == '#' || (r == '-' && i+1 < len(runes) && runes[i+1] == '-' &&
+			(i+2 == len(runes) || unicode.IsSpace(runes[i+2]))):
+			// 单行注释,MySQL 要求 -- 后跟空白,SELECT 1--1 不是注释
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// 多行注释,/*! ... */ 为 MySQL 可执行注释,原样保留
+			keep := i+2 < len(runes) && runes[i+2] == '!'
+			start := i
+			for i += 2; i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/'); i++ {
+			}
+			i++
+			if keep {
+				end := i + 1
+				if end > len(runes) {
+					end = len(runes)
+				}
+				current.WriteString(string(runes[start:end]))
+			} else {
+				current.WriteRune(' ')
+			}
+		case r == ';':
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return statements
+}
@@ -0,0 +1,207 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/oyjz/gosf"
+	"github.com/oyjz/gosf/dbtest"
+)
+
+func TestLoad(t *testing.T) {
+	source := fstest.MapFS{
+		"0002_add_email.up.sql":     {Data: []byte("ALTER TABLE user ADD email VARCHAR(64);")},
+		"0001_create_user.up.sql":   {Data: []byte("CREATE TABLE user (id INT);")},
+		"0001_create_user.down.sql": {Data: []byte("DROP TABLE user;")},
+		"README.md":                 {Data: []byte("ignored")},
+	}
+	migrations, err := load(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[0].Name != "create_user" || !migrations[0].HasDown {
+		t.Errorf("unexpected first migration %+v", migrations[0])
+	}
+	if migrations[1].Version != 2 || migrations[1].HasDown {
+		t.Errorf("unexpected second migration %+v", migrations[1])
+	}
+}
+
+func TestLoadConflict(t *testing.T) {
+	source := fstest.MapFS{
+		"0001_a.up.sql": {Data: []byte("SELECT 1")},
+		"0001_b.up.sql": {Data: []byte("SELECT 2")},
+	}
+	if _, err := load(source); err == nil {
+		t.Error("expected conflicting names error")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	text := `
+-- create table
+CREATE TABLE t (name VARCHAR(10) DEFAULT 'a;b'); # trailing comment;
+/* block; comment */ INSERT INTO t VALUES ("x;y"), ('it\'s;');
+;
+`
+	want := []string{
+		"CREATE TABLE t (name VARCHAR(10) DEFAULT 'a;b')",
+		`INSERT INTO t VALUES ("x;y"), ('it\'s;')`,
+	}
+	if got := splitStatements(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSplitStatementsMysqlComments(t *testing.T) {
+	// /*! */ 可执行注释原样保留,-- 后无空白时不是注释
+	text := "/*!40101 SET NAMES utf8mb4 */;\nSELECT 1--1;\nSELECT 2 --\n;SELECT 3 -- x; y\n"
+	want := []string{
+		"/*!40101 SET NAMES utf8mb4 */",
+		"SELECT 1--1",
+		"SELECT 2",
+		"SELECT 3",
+	}
+	if got := splitStatements(text); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// newMock 注册模拟数据库,返回迁移执行器
+func newMock(t *testing.T, source fs.FS) (*dbtest.Mock, *Migrator) {
+	t.Helper()
+	mock := dbtest.New()
+	m := &gosf.Mysql{}
+	if err := mock.Register(m, "base"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+	return mock, New(m.MustDB("base"), source)
+}
+
+// expectLock 预期获取锁与创建版本表
+func expectLock(mock *dbtest.Mock) {
+	mock.ExpectQuery("SELECT GET_LOCK(?, ?)").WithArgs(DefaultLockName, 60).
+		WillReturnRows(dbtest.NewRows("locked").AddRow(1))
+	mock.ExpectExecMatch("^CREATE TABLE IF NOT EXISTS `schema_migrations`")
+}
+
+// expectApplied 预期读取已执行的版本,dirty 为各版本的失败标记
+func expectApplied(mock *dbtest.Mock, dirty ...bool) {
+	rows := dbtest.NewRows("version", "name", "dirty", "applied_at")
+	for i, d := range dirty {
+		rows.AddRow(int64(i+1), "v", d, []byte("2024-01-02 03:04:05"))
+	}
+	mock.ExpectQuery("SELECT version, name, dirty, applied_at FROM `schema_migrations`").WillReturnRows(rows)
+}
+
+func expectRelease(mock *dbtest.Mock) {
+	mock.ExpectExec("SELECT RELEASE_LOCK(?)").WithArgs(DefaultLockName)
+}
+
+var mockSource = fstest.MapFS{
+	"0001_create_user.up.sql": {Data: []byte("CREATE TABLE user (id INT);")},
+	"0002_add_email.up.sql":   {Data: []byte("ALTER TABLE user ADD email VARCHAR(64);")},
+}
+
+func TestUpFailureMarksDirty(t *testing.T) {
+	mock, m := newMock(t, mockSource)
+	expectLock(mock)
+	expectApplied(mock)
+	mock.ExpectExec("INSERT INTO `schema_migrations` (version, name, dirty, applied_at) VALUES (?, ?, 1, ?)").
+		WithArgs(1, "create_user", dbtest.AnyArg)
+	mock.ExpectExec("CREATE TABLE user (id INT)").WillReturnError(errors.New("syntax error"))
+	// 失败时不清除失败标记,但要释放锁
+	expectRelease(mock)
+
+	err := m.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "database is dirty") {
+		t.Fatalf("expected dirty error, got %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpRefusesDirty(t *testing.T) {
+	mock, m := newMock(t, mockSource)
+	expectLock(mock)
+	expectApplied(mock, true)
+	expectRelease(mock)
+
+	if err := m.Up(context.Background()); !errors.Is(err, ErrDirty) {
+		t.Fatalf("expected ErrDirty, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestForceClearsDirty(t *testing.T) {
+	mock, m := newMock(t, mockSource)
+	expectLock(mock)
+	mock.ExpectExec("DELETE FROM `schema_migrations` WHERE version > ?").WithArgs(1)
+	mock.ExpectExec("UPDATE `schema_migrations` SET dirty = 0")
+	mock.ExpectExec("INSERT IGNORE INTO `schema_migrations` (version, name, dirty, applied_at) VALUES (?, ?, 0, ?)").
+		WithArgs(1, "create_user", dbtest.AnyArg)
+	expectRelease(mock)
+	// 清除后可以继续执行剩余的迁移
+	expectLock(mock)
+	expectApplied(mock, false)
+	mock.ExpectExec("INSERT INTO `schema_migrations` (version, name, dirty, applied_at) VALUES (?, ?, 1, ?)").
+		WithArgs(2, "add_email", dbtest.AnyArg)
+	mock.ExpectExec("ALTER TABLE user ADD email VARCHAR(64)")
+	mock.ExpectExec("UPDATE `schema_migrations` SET dirty = 0 WHERE version = ?").WithArgs(2)
+	expectRelease(mock)
+
+	ctx := context.Background()
+	if err := m.Force(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLockTimeout(t *testing.T) {
+	mock, m := newMock(t, mockSource)
+	mock.ExpectQuery("SELECT GET_LOCK(?, ?)").WithArgs(DefaultLockName, 60).
+		WillReturnRows(dbtest.NewRows("locked").AddRow(0))
+
+	if err := m.Up(context.Background()); err == nil || !strings.Contains(err.Error(), "timeout waiting for lock") {
+		t.Fatalf("expected lock timeout, got %v", err)
+	}
+	// 未获取到锁时不释放
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStatusTakesLock(t *testing.T) {
+	mock, m := newMock(t, mockSource)
+	expectLock(mock)
+	expectApplied(mock, false)
+	expectRelease(mock)
+
+	list, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !list[0].Applied || list[1].Applied {
+		t.Errorf("unexpected status %+v", list)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
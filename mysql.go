@@ -1,368 +1,604 @@
 package gosf
 
 /**
+db := NewMysql(config).MustDB("base")
 单条数据查询Get()方法
-AccountData := DB("base").Table("auth_account").Where("phone=?", PhoneNumber).Get()
+AccountData := db.Table("auth_account").Where("phone=?", PhoneNumber).Get()
 多条数据查询All()方法
-UserData := DB("base").Table("auth_user").Where("account_id=? AND type=? AND status<>?", AccountId, UserType, 2).Select( "account_id", "id AS user_id", "last_login", "real_pwd", "status").Get()
+UserData := db.Table("auth_user").Where("account_id=? AND type=? AND status<>?", AccountId, UserType, 2).Select( "account_id", "id AS user_id", "last_login", "real_pwd", "status").Get()
+构造器方法返回新的副本,不修改原构造器,公共条件可以在多个 goroutine 中复用
+base := db.Table("order").Where("uid=?", uid)
+paid := base.Where("status=?", 1).All()
+total := base.Count()
 */
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/oyjz/gosf/config"
-	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
-
-	_ "github.com/go-sql-driver/mysql"
+	"sync/atomic"
 )
 
-// 设置DB全部变量
-var once sync.Once
-var db *sql.DB
-var dbMap map[string]*sql.DB
+// ErrNoRows 查询结果为空，GetCtx、FetchOneCtx 未查到数据时返回，可用 errors.Is 判断
+var ErrNoRows = sql.ErrNoRows
+
+// ErrUnknownDB 数据库未注册,DB 获取未注册的名称时返回，可用 errors.Is 判断
+var ErrUnknownDB = errors.New("gosf: unknown database")
 
+// ErrNoDatabase 构造器未绑定数据库,Query 创建的构造器执行查询时返回，可用 errors.Is 判断
+var ErrNoDatabase = errors.New("gosf: query builder has no database, use DB().Table")
+
+// Mysql 命名数据库连接池集合,每个实例独立持有连接,零值可直接 Register 使用
 type Mysql struct {
-	dbs map[string]*sql.DB
+	mu     sync.RWMutex
+	dbs    map[string]*dbCluster
+	hooks  queryHooks
+	logger atomic.Pointer[Logger] // 从库健康状态变化日志,为空时使用标准输出
 }
 
 // DbPool 数据库操作处理结构体
 type DbPool struct {
-	pool            *sql.DB                  `数据库连接池`
-	tx              *sql.Tx                  `事务`
-	tableName       string                   `数据表名字`
-	selectCondition []string                 `选择条件`
-	whereCondition  []map[string]interface{} `查询条件`
-	groupCondition  []string                 `分组条件`
-	orderCondition  []string                 `排序条件`
-	lastSql         string
+	pool            *sql.DB // 数据库连接池,读写分离时为主库
+	cluster         *dbCluster
+	usePrimary      bool     // 读操作强制使用主库
+	tx              *sql.Tx  // 事务
+	txDepth         int      // 嵌套事务层级,用于生成保存点
+	tableName       string   // 数据表名字
+	selectCondition []string // 选择条件
+	whereCondition  Cond     // 查询条件
+	groupCondition  []string // 分组条件
+	orderCondition  []string // 排序条件
+	havingCondition Cond     // 分组过滤条件
+	joins           []joinClause
+	fromSub         *DbPool // FROM 子查询
+	fromAlias       string  // FROM 子查询别名
+	distinct        bool
+	sqlDialect      Dialect       // 指定的SQL方言,为空时使用数据库注册的方言
+	conflictColumns []string      // 唯一键冲突列,用于 SQLite、PostgreSQL 的 Upsert
+	trashed         int           // 软删除查询方式
+	withoutScopes   []string      // 忽略的全局条件
+	lastSql         *atomic.Value // 最后执行的SQL,同一来源的构造器共享,并发执行时可安全读取
+	tags            []string      // 查询标签
 	limit           int
+	limitSet        bool // 是否显式设置了 Limit,子查询未设置时不输出默认分页
 	page            int
 }
 
-// NewMysql 单例获取数据库实例，初始化后需要调用关闭数据库连接 defer p.Close()
+// NewMysql 按配置获取数据库实例，初始化后需要调用关闭数据库连接 defer p.Close()
+// 每次调用返回独立的实例,任意数据库无法连接时打印错误并退出
 func NewMysql(config config.Config) *Mysql {
-	once.Do(func() {
-		dbMap = make(map[string]*sql.DB)
-
-		var item map[string]string
-
-		err := config.GetAs("mysql", &item)
-		PanicErr(err, "config get error")
-
-		for name, addr := range item {
-			db, err = sql.Open("mysql", addr)
-			PanicErr(err, "sql open error")
-			// 设置连接池最大链接数--不能大于数据库设置的最大链接数
-			db.SetMaxOpenConns(1000)
-			// 设置最大空闲链接数--小于设置的链接数
-			db.SetMaxIdleConns(10)
-			// 设置数据库链接超时时间--不能大于数据库设置的超时时间
-			db.SetConnMaxLifetime(time.Second * 5)
-			dbMap[name] = db
-		}
-	})
+	m, err := OpenMysql(config)
+	PanicErr(err, "mysql init error")
+	return m
+}
 
-	return &Mysql{
-		dbs: dbMap,
+// OpenMysql 按配置打开 mysql 下的全部数据库,启动时逐个检测连通性,任意一个失败时关闭已打开的连接并返回错误
+func OpenMysql(config config.Config) (*Mysql, error) {
+	var items map[string]MysqlOptions
+	if err := config.GetAs("mysql", &items); err != nil {
+		return nil, fmt.Errorf("gosf: mysql config error: %w", err)
+	}
+	m := &Mysql{dbs: make(map[string]*dbCluster)}
+	for name, opts := range items {
+		if err := m.Open(name, opts); err != nil {
+			m.Close()
+			return nil, err
+		}
 	}
+	return m, nil
 }
 
-// DB 单例获取数据库实例，初始化后需要调用关闭数据库连接 defer p.Close()
-func (p *Mysql) DB(name string) *DbPool {
+// DB 获取命名数据库的查询构造器,名称未注册时返回 ErrUnknownDB
+func (p *Mysql) DB(name string) (*DbPool, error) {
+	p.mu.RLock()
+	c, ok := p.dbs[name]
+	p.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDB, name)
+	}
 	return &DbPool{
-		pool: dbMap[name],
+		pool:    c.primary,
+		cluster: c,
+		lastSql: &atomic.Value{},
+		page:    1,
+		limit:   10,
+	}, nil
+}
+
+// MustDB 获取命名数据库的查询构造器,名称未注册时panic
+func (p *Mysql) MustDB(name string) *DbPool {
+	db, err := p.DB(name)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// Open 按配置打开并注册命名数据库,配置了从库时一并打开,名称已存在时返回错误
+func (p *Mysql) Open(name string, opts MysqlOptions) error {
+	if p.Has(name) {
+		return fmt.Errorf("gosf: database %q already registered", name)
 	}
+	primary, err := openMysqlDB(name, opts)
+	if err != nil {
+		return err
+	}
+	replicas := make([]*sql.DB, 0, len(opts.Replicas))
+	closeAll := func() {
+		_ = primary.Close()
+		for _, db := range replicas {
+			_ = db.Close()
+		}
+	}
+	for i, replicaOpts := range opts.Replicas {
+		db, err := openMysqlDB(fmt.Sprintf("%s.replicas[%d]", name, i), replicaOpts.inherit(opts))
+		if err != nil {
+			closeAll()
+			return err
+		}
+		replicas = append(replicas, db)
+	}
+	c := newCluster(name, primary, replicas, opts.HealthCheckInterval, &p.logger)
+	c.loc = dsnLocation(opts.Dsn)
+	if err = p.register(name, c); err != nil {
+		closeAll()
+		return err
+	}
+	return nil
+}
+
+// Register 注册已打开的 MySQL 连接池,可同时指定从库,名称已存在时返回错误,连接池由 Mysql 负责关闭
+func (p *Mysql) Register(name string, db *sql.DB, replicas ...*sql.DB) error {
+	return p.RegisterWith(name, MysqlDialect, db, replicas...)
+}
+
+// RegisterWith 按指定方言注册已打开的连接池,用于 SQLite、PostgreSQL 等其他数据库
+func (p *Mysql) RegisterWith(name string, dialect Dialect, db *sql.DB, replicas ...*sql.DB) error {
+	if db == nil {
+		return fmt.Errorf("gosf: database %q is nil", name)
+	}
+	c := newCluster(name, db, replicas, 0, &p.logger)
+	c.dialect = dialect
+	if err := p.register(name, c); err != nil {
+		c.stopOnce.Do(func() { close(c.stop) })
+		return err
+	}
+	return nil
+}
+
+func (p *Mysql) register(name string, c *dbCluster) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.dbs[name]; ok {
+		return fmt.Errorf("gosf: database %q already registered", name)
+	}
+	if p.dbs == nil {
+		p.dbs = make(map[string]*dbCluster)
+	}
+	c.hooks = &p.hooks
+	p.dbs[name] = c
+	return nil
+}
+
+// Replace 注册或替换命名数据库,可同时指定从库,被替换的连接池会被关闭,沿用被替换数据库的方言
+func (p *Mysql) Replace(name string, db *sql.DB, replicas ...*sql.DB) error {
+	if db == nil {
+		return fmt.Errorf("gosf: database %q is nil", name)
+	}
+	c := newCluster(name, db, replicas, 0, &p.logger)
+	p.mu.Lock()
+	old := p.dbs[name]
+	if old != nil {
+		c.dialect = old.dialect
+	}
+	if p.dbs == nil {
+		p.dbs = make(map[string]*dbCluster)
+	}
+	c.hooks = &p.hooks
+	p.dbs[name] = c
+	p.mu.Unlock()
+	if old != nil {
+		return old.close()
+	}
+	return nil
+}
+
+// SetLogger 设置从库健康状态变化的日志,为nil时使用标准输出
+func (p *Mysql) SetLogger(logger *Logger) {
+	p.logger.Store(logger)
+}
+
+// Remove 移除并关闭命名数据库
+func (p *Mysql) Remove(name string) error {
+	p.mu.Lock()
+	c, ok := p.dbs[name]
+	delete(p.dbs, name)
+	p.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownDB, name)
+	}
+	return c.close()
+}
+
+// Has 是否已注册命名数据库
+func (p *Mysql) Has(name string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.dbs[name]
+	return ok
+}
+
+// Names 已注册的数据库名称,按字母排序
+func (p *Mysql) Names() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	names := make([]string, 0, len(p.dbs))
+	for name := range p.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func (p *DbPool) GetPool() *sql.DB {
 	return p.pool
 }
 
-// Table 查询数据表获取
+// Clone 复制构造器,复制后的修改互不影响
+func (p *DbPool) Clone() *DbPool {
+	return p.clone()
+}
+
+// clone 浅复制,追加类的切片限制容量,避免 append 写入共享的底层数组
+func (p *DbPool) clone() *DbPool {
+	q := *p
+	q.joins = q.joins[:len(q.joins):len(q.joins)]
+	q.tags = q.tags[:len(q.tags):len(q.tags)]
+	return &q
+}
+
+// Table 基于当前连接创建指定数据表的新查询,不继承查询条件
 func (p *DbPool) Table(name string) *DbPool {
-	p.tableName = name
-	p.whereCondition = nil
-	p.selectCondition = nil
-	p.groupCondition = nil
-	p.orderCondition = nil
-	p.lastSql = ""
-	p.page = 1
-	p.limit = 10
-	return p
+	q := p.session()
+	q.tableName = name
+	return q
 }
 
 // LastSql 获取最后执行SQL
 func (p *DbPool) LastSql() string {
-	return p.lastSql
+	if p.lastSql == nil {
+		return ""
+	}
+	sql, _ := p.lastSql.Load().(string)
+	return sql
+}
+
+// setLastSql 记录最后执行的SQL
+func (p *DbPool) setLastSql(query string) {
+	if p.lastSql != nil {
+		p.lastSql.Store(query)
+	}
 }
 
 // Select 查询select条件入参,入参类似python的args
 func (p *DbPool) Select(params ...string) *DbPool {
-	p.selectCondition = params
-	return p
+	q := p.clone()
+	q.selectCondition = params
+	return q
 }
 
-// Where 查询where条件入参,入参类似于python的args
+// Where 查询where条件入参,入参类似于python的args,多次调用以AND连接
+// query 可以是带 ? 占位符的SQL片段、Cond 条件或 map[string]interface{}
 func (p *DbPool) Where(query interface{}, values ...interface{}) *DbPool {
-	p.whereCondition = append(p.whereCondition, map[string]interface{}{"query": query, "args": values})
-	return p
+	cond := toCond(query, values)
+	q := p.clone()
+	if q.whereCondition == nil {
+		q.whereCondition = cond
+	} else {
+		q.whereCondition = And(q.whereCondition, cond)
+	}
+	return q
+}
+
+// OrWhere 与已有的where条件以OR连接,入参同 Where
+func (p *DbPool) OrWhere(query interface{}, values ...interface{}) *DbPool {
+	cond := toCond(query, values)
+	q := p.clone()
+	if q.whereCondition == nil {
+		q.whereCondition = cond
+	} else {
+		q.whereCondition = Or(q.whereCondition, cond)
+	}
+	return q
 }
 
 // GroupBy 定义数据库分组函数,入参类似于python的args
 func (p *DbPool) GroupBy(params ...string) *DbPool {
-	p.groupCondition = params
-	return p
+	q := p.clone()
+	q.groupCondition = params
+	return q
 }
 
 // Limit
 func (p *DbPool) Limit(limit int) *DbPool {
-	p.limit = limit
-	return p
+	q := p.clone()
+	q.limit = limit
+	q.limitSet = true
+	return q
 }
 
 // Page
 func (p *DbPool) Page(page int) *DbPool {
-	p.page = page
-	return p
+	q := p.clone()
+	q.page = page
+	return q
 }
 
-// Tx 设置事务
+// Tx 设置事务,设置后读写均在该事务中执行
 func (p *DbPool) Tx(tx *sql.Tx) *DbPool {
-	p.tx = tx
-	return p
+	q := p.clone()
+	q.tx = tx
+	q.txDepth = 0
+	return q
 }
 
 // OrderBy 定义数据库排序函数,入参类似于python的args
 func (p *DbPool) OrderBy(params ...string) *DbPool {
-	p.orderCondition = params
-	return p
+	q := p.clone()
+	q.orderCondition = params
+	return q
+}
+
+// SQL拼接处理,返回带占位符的SQL与参数
+func (p *DbPool) sql(ctx context.Context) (string, []interface{}, error) {
+	w := p.writer(ctx)
+	p.writeSelect(w, false)
+	if w.err != nil {
+		return "", nil, w.err
+	}
+	return w.String(), w.args, nil
 }
 
-// SQL拼接处理
-func (p *DbPool) sql() string {
+// writeSelect 拼接SELECT语句, sub 为 true 时表示作为子查询或游标输出,未显式设置 Limit 时不分页
+func (p *DbPool) writeSelect(w *sqlWriter, sub bool) {
+	w.WriteString("SELECT ")
+	if p.distinct {
+		w.WriteString("DISTINCT ")
+	}
 	// 处理select条件
 	SelectFilter := strings.Join(p.selectCondition, ",")
 	// 没有设置获取数据字段,默认查询全部
 	if len(p.selectCondition) == 0 {
 		SelectFilter = "*"
 	}
+	w.WriteString(SelectFilter)
+	// 处理数据来源
+	w.WriteString(" FROM ")
+	if p.fromSub != nil {
+		w.writeArg(p.fromSub)
+		w.WriteString(" AS " + w.quoteColumn(p.fromAlias))
+	} else {
+		w.WriteString(w.quoteTable(p.tableName))
+	}
+	// 处理关联条件
+	for _, join := range p.joins {
+		join.writeTo(w)
+	}
 	// 处理where条件
-	WhereFilter := p.handlerWhere()
+	p.handlerWhere(w)
 	// 处理分组条件
 	GroupFilter := strings.Join(p.groupCondition, ",")
+	if len(GroupFilter) > 0 {
+		w.WriteString(" GROUP BY " + GroupFilter)
+	}
+	if p.havingCondition != nil {
+		w.WriteString(" HAVING ")
+		p.havingCondition.writeTo(w)
+	}
 	// 处理排序条件
 	OrderFilter := strings.Join(p.orderCondition, ",")
 	if len(OrderFilter) > 0 {
-		OrderFilter = " ORDER BY " + OrderFilter
+		w.WriteString(" ORDER BY " + OrderFilter)
 	}
-	LimitFileter := ""
-	if p.limit > 0 {
-		if p.page == 0 {
-			p.page = 1
+	// 子查询未显式设置 Limit 时不分页
+	if p.limit > 0 && (!sub || p.limitSet) {
+		page := p.page
+		if page == 0 {
+			page = 1
 		}
-		LimitFileter = fmt.Sprintf(" LIMIT %d, %d", (p.page-1)*p.limit, p.limit)
+		w.WriteString(w.sqlDialect().Limit((page-1)*p.limit, p.limit))
 	}
-	// 格式化生成SQL
-	Sql := fmt.Sprintf("SELECT %v FROM `%v` %v %v %v %s", SelectFilter, p.tableName, WhereFilter, GroupFilter, OrderFilter, LimitFileter)
-	return Sql
 }
 
 // 数据库返回数据处理,返回数据类型为slice,slice内层为map
-func dealMysqlRows(rows *sql.Rows) []map[string]interface{} {
+func dealMysqlRows(rows *sql.Rows) ([]map[string]interface{}, error) {
 	defer closeRows(rows)
-	// 获取列名
-	columns, err := rows.Columns()
-	PanicErr(err, "rows columns error")
-	columnTypes, _ := rows.ColumnTypes()
-	// 获取每列的数据类型
-	ColumnTypeMap := make(map[string]string)
-	for _, v := range columnTypes {
-		ColumnTypeMap[v.Name()] = v.DatabaseTypeName()
-	}
-	// 定义返回参数的slice
-	retValues := make([]sql.RawBytes, len(columns))
-	// 定义数据列名的slice
-	scanArgs := make([]interface{}, len(retValues))
-	// 数据列赋值
-	for i := range retValues {
-		scanArgs[i] = &retValues[i]
+	mapper, err := newRowMapper(rows)
+	if err != nil {
+		return nil, err
 	}
 	// 定义返回数据类型slice
 	var resList []map[string]interface{}
 	// 返回数据赋值
 	for rows.Next() {
-		// 检测数据列是否超出
-		err = rows.Scan(scanArgs...)
-		PanicErr(err, "rows scan error")
-		// 内层数据格式
-		rowMap := make(map[string]interface{})
-		for i, colVal := range retValues {
-			if colVal != nil {
-				keyName := columns[i]
-				value := string(colVal)
-
-				typeName := ColumnTypeMap[keyName]
-				if strings.Contains(typeName, "INT") {
-					newValue, _ := strconv.Atoi(value)
-					rowMap[keyName] = newValue
-				} else if strings.Contains(typeName, "DECIMAL") {
-					newValue, _ := strconv.ParseFloat(value, 64)
-					rowMap[keyName] = newValue
-				} else {
-					rowMap[keyName] = value
-				}
-			}
+		rowMap, err := mapper.scan(rows)
+		if err != nil {
+			return nil, err
 		}
 		resList = append(resList, rowMap)
 	}
-	return resList
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows iterate error: %w", err)
+	}
+	return resList, nil
 }
 
-// Get 获取第一条数据,返回数据类型为map
-func (p *DbPool) Get() map[string]interface{} {
-	var RetOne map[string]interface{}
-	p.Limit(1)
-	GetSql := p.sql()
-	p.lastSql = GetSql
-	rows, err := p.pool.Query(GetSql)
-	PanicErr(err, "query get error")
-	// 数据获取
-	RetMap := dealMysqlRows(rows)
-	if len(RetMap) > 0 {
-		RetOne = RetMap[0]
-	}
-	return RetOne
+// rowMapper 将数据行转换为map,INT 转为 int,DECIMAL 转为 float64,其余为字符串,NULL 为 nil
+type rowMapper struct {
+	columns   []string
+	typeNames []string
+	retValues []sql.RawBytes
+	scanArgs  []interface{}
 }
 
-// All 获取多条数据,返回数据类型为slice,slice内层为map
-func (p *DbPool) All() []map[string]interface{} {
-	GetSql := p.sql()
-	p.lastSql = GetSql
-	rows, err := p.pool.Query(GetSql)
-	PanicErr(err, "query all error")
-	// 数据获取
-	RetMap := dealMysqlRows(rows)
-	return RetMap
+func newRowMapper(rows *sql.Rows) (*rowMapper, error) {
+	// 获取列名
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("rows columns error: %w", err)
+	}
+	// 获取每列的数据类型
+	typeNames := make([]string, len(columns))
+	if columnTypes, err := rows.ColumnTypes(); err == nil {
+		for i, v := range columnTypes {
+			typeNames[i] = v.DatabaseTypeName()
+		}
+	}
+	m := &rowMapper{
+		columns:   columns,
+		typeNames: typeNames,
+		retValues: make([]sql.RawBytes, len(columns)),
+		scanArgs:  make([]interface{}, len(columns)),
+	}
+	for i := range m.retValues {
+		m.scanArgs[i] = &m.retValues[i]
+	}
+	return m, nil
 }
 
-// Insert 定义创建数据方法,返回最后的ID
-func (p *DbPool) Insert(params map[string]interface{}) (lastId int, err error) {
-	// defer func() {
-	// 	fmt.Println(p.lastSql)
-	// }()
-	// 自定待创建的函数和参数
-	InsertCols, InsertArgs := "", ""
-	for k, v := range params {
-		// 数据列只能为string类型
-		if InsertCols == "" {
-			InsertCols += fmt.Sprintf("%s", k)
-		} else {
-			InsertCols += fmt.Sprintf(",%s", k)
+// scan 转换当前行
+func (m *rowMapper) scan(rows *sql.Rows) (map[string]interface{}, error) {
+	if err := rows.Scan(m.scanArgs...); err != nil {
+		return nil, fmt.Errorf("rows scan error: %w", err)
+	}
+	// 内层数据格式
+	rowMap := make(map[string]interface{}, len(m.columns))
+	for i, colVal := range m.retValues {
+		keyName := m.columns[i]
+		// NULL 值保留键名,值为nil
+		if colVal == nil {
+			rowMap[keyName] = nil
+			continue
 		}
-		// 判断数据类型,类型断言判断
-		switch v.(type) {
-		case int:
-			if InsertArgs == "" {
-				InsertArgs += fmt.Sprintf("%d", v)
-			} else {
-				InsertArgs += fmt.Sprintf(",%d", v)
-			}
-		case string:
-			temp := strings.Replace(v.(string), "'", "\\'", -1)
-			if InsertArgs == "" {
-				InsertArgs += fmt.Sprintf("'%s'", temp)
-			} else {
-				InsertArgs += fmt.Sprintf(",'%s'", temp)
-			}
-		case float64:
-			if InsertArgs == "" {
-				InsertArgs += fmt.Sprintf("%f", v)
-			} else {
-				InsertArgs += fmt.Sprintf(",%f", v)
-			}
-		default:
-			if InsertArgs == "" {
-				InsertArgs += fmt.Sprintf("%v", v)
-			} else {
-				InsertArgs += fmt.Sprintf(",%v", v)
+		value := string(colVal)
+
+		typeName := m.typeNames[i]
+		if strings.Contains(typeName, "INT") {
+			newValue, err := strconv.Atoi(value)
+			if err != nil {
+				// BIGINT UNSIGNED 超出int范围时返回uint64
+				u, uErr := strconv.ParseUint(value, 10, 64)
+				if uErr != nil {
+					return nil, fmt.Errorf("scan column %q: %w", keyName, err)
+				}
+				rowMap[keyName] = u
+				continue
 			}
+			rowMap[keyName] = newValue
+		} else if strings.Contains(typeName, "DECIMAL") {
+			newValue, _ := strconv.ParseFloat(value, 64)
+			rowMap[keyName] = newValue
+		} else {
+			rowMap[keyName] = value
 		}
 	}
-	// 组合数据写入SQL
-	InsertSql := fmt.Sprintf("INSERT INTO `%v` (%v) VALUES (%v);", p.tableName, InsertCols, InsertArgs)
-	p.lastSql = InsertSql
+	return rowMap, nil
+}
 
-	// 执行，判断是否存在事务
-	var retData sql.Result
-	if p.tx == nil {
-		retData, err = p.pool.Exec(InsertSql)
-	} else {
-		retData, err = p.tx.Exec(InsertSql)
+// queryRows 执行查询SQL，记录最后执行的SQL
+// 读写分离时使用从库,从库连接异常时移出轮询并改用主库重试
+func (p *DbPool) queryRows(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	if p.pool == nil && p.tx == nil {
+		return nil, ErrNoDatabase
+	}
+	p.run(ctx, false, query, args, func(ctx context.Context) (int64, error) {
+		conn, r := p.readConn()
+		rows, err = conn.QueryContext(ctx, query, args...)
+		if err != nil && r != nil && isConnError(err) {
+			p.cluster.markUnhealthy(r, err)
+			rows, err = p.pool.QueryContext(ctx, query, args...)
+		}
+		return 0, err
+	})
+	return rows, err
+}
+
+// query 执行查询SQL，返回数据类型为slice,slice内层为map
+func (p *DbPool) query(ctx context.Context, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := p.queryRows(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return dealMysqlRows(rows)
+}
+
+// Get 获取第一条数据,返回数据类型为map
+func (p *DbPool) Get() map[string]interface{} {
+	RetOne, err := p.GetCtx(context.Background())
+	if err == ErrNoRows {
+		return nil
 	}
+	PanicErr(err, "query get error")
+	return RetOne
+}
 
+// GetCtx 获取第一条数据,未查到数据时返回 ErrNoRows
+func (p *DbPool) GetCtx(ctx context.Context) (map[string]interface{}, error) {
+	q := p.Limit(1)
+	GetSql, args, err := q.sql(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	LastId, err := retData.LastInsertId()
+	RetMap, err := q.query(ctx, GetSql, args...)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	if len(RetMap) == 0 {
+		return nil, ErrNoRows
 	}
-	return int(LastId), err
+	return RetMap[0], nil
 }
 
-// Update 定义更新数据方法,返回影响的行数
-func (p *DbPool) Update(params map[string]interface{}) (affectRows int, err error) {
-	// defer func() {
-	// 	fmt.Println(p.lastSql)
-	// }()
-	// 处理where条件
-	WhereFilter := p.handlerWhere()
-	// 定义待创建的函数和参数
-	UpdateArgs := ""
-	for k, v := range params {
-		// 数据列只能为string类型
-		if UpdateArgs == "" {
-			// 判断数据类型,类型断言判断
-			switch v.(type) {
-			case int:
-				UpdateArgs += fmt.Sprintf("%s=%d", k, v)
-			case string:
-				temp := strings.Replace(v.(string), "'", "\\'", -1)
-				UpdateArgs += fmt.Sprintf("%s='%s'", k, temp)
-			case float64:
-				UpdateArgs += fmt.Sprintf("%s=%f", k, v)
-			default:
-				UpdateArgs += fmt.Sprintf("%v=%v", k, v)
-			}
-		} else {
-			// 判断数据类型,类型断言判断
-			switch v.(type) {
-			case int:
-				UpdateArgs += fmt.Sprintf(",%s=%d", k, v)
-			case string:
-				temp := strings.Replace(v.(string), "'", "\\'", -1)
-				UpdateArgs += fmt.Sprintf(",%s='%s'", k, temp)
-			case float64:
-				UpdateArgs += fmt.Sprintf(",%s=%f", k, v)
-			default:
-				UpdateArgs += fmt.Sprintf(",%v=%v", k, v)
-			}
-		}
+// All 获取多条数据,返回数据类型为slice,slice内层为map
+func (p *DbPool) All() []map[string]interface{} {
+	RetMap, err := p.AllCtx(context.Background())
+	PanicErr(err, "query all error")
+	return RetMap
+}
+
+// AllCtx 获取多条数据,没有数据时返回空slice
+func (p *DbPool) AllCtx(ctx context.Context) ([]map[string]interface{}, error) {
+	GetSql, args, err := p.sql(ctx)
+	if err != nil {
+		return nil, err
 	}
-	// 组合数据更新SQL
-	UpdateSql := fmt.Sprintf("UPDATE `%v` SET %v %v;", p.tableName, UpdateArgs, WhereFilter)
+	return p.query(ctx, GetSql, args...)
+}
 
-	p.lastSql = UpdateSql
-	// 执行，判断是否存在事务
-	var retData sql.Result
-	if p.tx == nil {
-		retData, err = p.pool.Exec(UpdateSql)
-	} else {
-		retData, err = p.tx.Exec(UpdateSql)
+// exec 执行写入SQL
+func (p *DbPool) exec(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	if p.pool == nil && p.tx == nil {
+		return nil, ErrNoDatabase
 	}
+	p.run(ctx, true, query, args, func(ctx context.Context) (int64, error) {
+		result, err = p.conn().ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		affected, _ := result.RowsAffected()
+		return affected, nil
+	})
+	return result, err
+}
+
+// execAffected 执行写入SQL,返回影响的行数
+func (p *DbPool) execAffected(ctx context.Context, query string, args ...interface{}) (int, error) {
+	retData, err := p.exec(ctx, query, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -373,166 +609,109 @@ func (p *DbPool) Update(params map[string]interface{}) (affectRows int, err erro
 	return int(ARows), nil
 }
 
-// 处理where条件
-func (p *DbPool) handlerWhere() string {
-	// 匿名函数interface转slice--需要时调用
-	fn := func(arr interface{}) []interface{} {
-		v := reflect.ValueOf(arr)
-		if v.Kind() != reflect.Slice {
-			panic("The Where params Valid")
-		}
-		vLen := v.Len()
-		ret := make([]interface{}, vLen)
-		for i := 0; i < vLen; i++ {
-			ret[i] = v.Index(i).Interface()
+// Insert 定义创建数据方法,返回最后的ID
+func (p *DbPool) Insert(params map[string]interface{}) (lastId int, err error) {
+	return p.insert(InsertDefault, params, nil)
+}
+
+// Update 定义更新数据方法,返回影响的行数
+func (p *DbPool) Update(params map[string]interface{}) (affectRows int, err error) {
+	if len(params) == 0 {
+		return 0, errors.New("gosf: update without columns")
+	}
+	params = p.withTimestamps(params, false)
+	w := p.writer(context.Background())
+	w.WriteString(fmt.Sprintf("UPDATE %v SET ", w.quoteTable(p.tableName)))
+	for i, k := range sortedKeys(params) {
+		if i > 0 {
+			w.WriteString(",")
 		}
-		return ret
+		w.WriteString(w.quoteColumn(k) + "=")
+		w.writeArg(params[k])
 	}
 	// 处理where条件
-	WhereFilter := ""
-	if len(p.whereCondition) > 0 && len(p.whereCondition[0]) > 0 {
-
-		for _, whereItem := range p.whereCondition {
-
-			FilterList := strings.Split(strings.ToLower(whereItem["query"].(string)), "and")
-			// 匿名函数处理where入参, interface转slice
-			WhereList := fn(whereItem["args"])
-			// 组合where条件
-			for index, value := range FilterList {
-				// 参数分割,并去除空格
-				NewValue := strings.TrimSpace(strings.Split(value, "?")[0])
-				if len(WhereList) <= index {
-					WhereFilter += fmt.Sprintf("WHERE %v", NewValue)
-					continue
-				}
-				WhereValue := WhereList[index]
-				// 入参类型断言
-				switch reflect.ValueOf(WhereValue).Kind() {
-				case reflect.Int:
-					if WhereFilter == "" {
-						WhereFilter += fmt.Sprintf("WHERE %v%d", NewValue, WhereList[index])
-					} else {
-						WhereFilter += fmt.Sprintf(" AND %v%d", NewValue, WhereList[index])
-					}
-				case reflect.String:
-					temp := strings.Replace(WhereList[index].(string), "'", "\\'", -1)
-					if WhereFilter == "" {
-						WhereFilter += fmt.Sprintf("WHERE %v '%v'", NewValue, temp)
-					} else {
-						WhereFilter += fmt.Sprintf(" AND %v '%v'", NewValue, temp)
-					}
-				case reflect.Slice:
-					// 匿名函数处理where入参, interface转slice
-					NewList := fn(WhereValue)
-					FilterWhere := ""
-					for _, v := range NewList {
-						switch reflect.ValueOf(v).Kind() {
-						case reflect.Int:
-							if FilterWhere == "" {
-								FilterWhere += fmt.Sprintf("%d", v)
-							} else {
-								FilterWhere += fmt.Sprintf(",%d", v)
-							}
-						case reflect.String:
-							if FilterWhere == "" {
-								FilterWhere += fmt.Sprintf("'%v'", v)
-							} else {
-								FilterWhere += fmt.Sprintf(",'%v'", v)
-							}
-						default:
-							panic("1001:The params Valid")
-						}
-					}
-					if WhereFilter == "" {
-						WhereFilter += fmt.Sprintf("WHERE %v (%v)", NewValue, FilterWhere)
-					} else {
-						WhereFilter += fmt.Sprintf(" AND %v (%v)", NewValue, FilterWhere)
-					}
-				default:
-					panic("1002:The params Valid")
-				}
-			}
-		}
+	p.handlerWhere(w)
+	if w.err != nil {
+		return 0, w.err
 	}
-	return WhereFilter
+	return p.execAffected(context.Background(), w.String(), w.args...)
 }
 
-// Delete 定义删除数据方法
-func (p *DbPool) Delete() (affectRows int, err error) {
-	// 处理where条件
-	WhereFilter := p.handlerWhere()
-	// 组合删除数据SQL
-	DeleteSql := fmt.Sprintf("DELETE FROM `%v` %v", p.tableName, WhereFilter)
-	p.lastSql = DeleteSql
-
-	// 执行，判断是否存在事务
-	var retData sql.Result
-	if p.tx == nil {
-		retData, err = p.pool.Exec(DeleteSql)
-	} else {
-		retData, err = p.tx.Exec(DeleteSql)
-	}
-
-	if err != nil {
-		return 0, err
+// 处理where条件,软删除与全局条件以AND追加
+func (p *DbPool) handlerWhere(w *sqlWriter) {
+	where := p.whereCondition
+	if conds := p.scopeConds(w.ctx); len(conds) > 0 {
+		if where != nil {
+			conds = append([]Cond{where}, conds...)
+		}
+		where = And(conds...)
 	}
-	ARows, err := retData.RowsAffected()
-	if err != nil {
-		return 0, err
+	if where != nil {
+		w.WriteString(" WHERE ")
+		where.writeTo(w)
 	}
-	return int(ARows), nil
 }
 
-// Execute 查询执行SQL方法
-func (p *DbPool) Execute(Sql string) (affectRows int, err error) {
-	p.lastSql = Sql
-
-	// 执行，判断是否存在事务
-	var retData sql.Result
-	if p.tx == nil {
-		retData, err = p.pool.Exec(Sql)
-	} else {
-		retData, err = p.tx.Exec(Sql)
+// sortedKeys 按字母顺序返回map的键,保证生成的SQL稳定
+func sortedKeys(params map[string]interface{}) []string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
+}
 
-	if err != nil {
-		return 0, err
-	}
-	ARows, err := retData.RowsAffected()
-	if err != nil {
-		return 0, err
+// Delete 定义删除数据方法,表开启软删除时更新删除时间列
+func (p *DbPool) Delete() (affectRows int, err error) {
+	if opts, ok := p.tableOptions(); ok && opts.SoftDelete {
+		return p.Update(map[string]interface{}{opts.DeletedAt: opts.Now()})
 	}
-	return int(ARows), nil
+	return p.ForceDelete()
+}
+
+// Execute 查询执行SQL方法,args 为 ? 占位符参数
+func (p *DbPool) Execute(Sql string, args ...interface{}) (affectRows int, err error) {
+	return p.execAffected(context.Background(), Sql, args...)
 }
 
 // FetchOne 定义执行SQL返回一条数据方法
 func (p *DbPool) FetchOne(Sql string) map[string]interface{} {
-	var RetOne map[string]interface{}
-	p.lastSql = Sql
-	rows, err := p.pool.Query(Sql)
-	PanicErr(err, "fetch one error")
-	// 数据获取
-	RetMap := dealMysqlRows(rows)
-	if len(RetMap) > 0 {
-		RetOne = RetMap[0]
+	RetOne, err := p.FetchOneCtx(context.Background(), Sql)
+	if err == ErrNoRows {
+		return nil
 	}
+	PanicErr(err, "fetch one error")
 	return RetOne
 }
 
+// FetchOneCtx 执行SQL返回一条数据,未查到数据时返回 ErrNoRows
+func (p *DbPool) FetchOneCtx(ctx context.Context, Sql string, args ...interface{}) (map[string]interface{}, error) {
+	RetMap, err := p.query(ctx, Sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(RetMap) == 0 {
+		return nil, ErrNoRows
+	}
+	return RetMap[0], nil
+}
+
 // FetchAll 定义执行SQL返回多条数据方法
 func (p *DbPool) FetchAll(Sql string) []map[string]interface{} {
-	p.lastSql = Sql
-	rows, err := p.pool.Query(Sql)
+	RetMap, err := p.FetchAllCtx(context.Background(), Sql)
 	PanicErr(err, "fetch all error")
-	// 数据获取
-	RetMap := dealMysqlRows(rows)
 	return RetMap
 }
 
+// FetchAllCtx 执行SQL返回多条数据
+func (p *DbPool) FetchAllCtx(ctx context.Context, Sql string, args ...interface{}) ([]map[string]interface{}, error) {
+	return p.query(ctx, Sql, args...)
+}
+
 // 关闭行,释放链接
 func closeRows(r *sql.Rows) {
-	err := r.Close()
-	PanicErr(err, "close rows error")
+	_ = r.Close()
 }
 
 func (p *DbPool) Close() {
@@ -541,96 +720,41 @@ func (p *DbPool) Close() {
 	}
 }
 
+// Close 关闭全部数据库连接
 func (p *Mysql) Close() {
-	if p.dbs != nil {
-		for _, dbPool := range p.dbs {
-			dbPool.Close()
-		}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, c := range p.dbs {
+		_ = c.close()
+		delete(p.dbs, name)
 	}
 }
 
-// BatchInsert 批量插入
+// BatchInsert 批量插入,每行的列必须相同,数据量大时自动分批并在事务中执行,返回影响的行数
 func (p *DbPool) BatchInsert(params []map[string]interface{}) (affectRows int, err error) {
-	// 自定待创建的函数和参数
-	InsertCols, InsertArgsList := "", ""
-	for k := range params[0] {
-		// 数据列只能为string类型
-		if InsertCols == "" {
-			InsertCols += fmt.Sprintf("%s", k)
-		} else {
-			InsertCols += fmt.Sprintf(",%s", k)
-		}
-	}
-	for _, data := range params {
-		InsertArgs := ""
-		for _, v := range data {
-			// 判断数据类型,类型断言判断
-			switch v.(type) {
-			case int:
-				if InsertArgs == "" {
-					InsertArgs += fmt.Sprintf("%d", v)
-				} else {
-					InsertArgs += fmt.Sprintf(",%d", v)
-				}
-			case string:
-				temp := strings.Replace(v.(string), "'", "\\'", -1)
-				if InsertArgs == "" {
-					InsertArgs += fmt.Sprintf("'%s'", temp)
-				} else {
-					InsertArgs += fmt.Sprintf(",'%s'", temp)
-				}
-			case float64:
-				if InsertArgs == "" {
-					InsertArgs += fmt.Sprintf("%f", v)
-				} else {
-					InsertArgs += fmt.Sprintf(",%f", v)
-				}
-			default:
-				if InsertArgs == "" {
-					InsertArgs += fmt.Sprintf("%v", v)
-				} else {
-					InsertArgs += fmt.Sprintf(",%v", v)
-				}
-			}
-		}
-		if InsertArgs != "" {
-			if InsertArgsList == "" {
-				InsertArgsList += fmt.Sprintf("(%v)", InsertArgs)
-			} else {
-				InsertArgsList += fmt.Sprintf(",(%v)", InsertArgs)
-			}
-		}
-	}
-
-	// 组合数据写入SQL
-	InsertSql := fmt.Sprintf("INSERT INTO `%v` (%v) VALUES %v;", p.tableName, InsertCols, InsertArgsList)
-	p.lastSql = InsertSql
-
-	// 执行，判断是否存在事务
-	var retData sql.Result
-	if p.tx == nil {
-		retData, err = p.pool.Exec(InsertSql)
-	} else {
-		retData, err = p.tx.Exec(InsertSql)
-	}
-
+	result, err := p.BatchInsertCtx(context.Background(), params, BatchOptions{})
 	if err != nil {
 		return 0, err
 	}
-	ARows, err := retData.RowsAffected()
-	if err != nil {
-		return 0, err
-	}
-	return int(ARows), nil
+	return result.Affected, nil
 }
 
 // Count 查询记录数
 func (p *DbPool) Count() int {
-	p.Select("count(*) as count")
-	GetSql := p.sql()
-	p.lastSql = GetSql
-	count := 0
-	err := p.pool.QueryRow(GetSql).Scan(&count)
+	count, err := p.CountCtx(context.Background())
 	PanicErr(err, "query count error")
 	return count
 }
+
+// CountCtx 查询记录数,不修改当前构造器的 select、排序与分页条件
+// 设置了 GroupBy 或 Distinct 时统计分组或去重后的行数
+func (p *DbPool) CountCtx(ctx context.Context) (int, error) {
+	if len(p.groupCondition) > 0 || p.distinct {
+		return p.countGrouped(ctx)
+	}
+	count := 0
+	if err := p.scalar(ctx, "count(*) as count", &count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,188 @@
+package gosf
+
+/**
+聚合查询,不修改当前构造器,出错时返回错误
+total, err := db.Table("order").Where("status=?", 1).Sum(ctx, "amount")
+avg, err := db.Table("order").Avg(ctx, "amount")
+var last time.Time
+err = db.Table("order").Max(ctx, "created_at", &last)
+ok, err := db.Table("user").Where("phone=?", phone).Exists(ctx)
+var ids []int64
+err = db.Table("order").Where("status=?", 1).Pluck(ctx, "id", &ids)
+分组聚合,结果写入 map,键为分组列的值
+var counts map[int]int
+err = db.Table("order").GroupCount(ctx, "status", &counts)
+var amounts map[string]float64
+err = db.Table("order").GroupSum(ctx, "city", "amount", &amounts)
+设置了 GroupBy 或 Distinct 时 Count 统计分组后的行数
+*/
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// aggregate 生成只查询聚合表达式的构造器,去除排序与分页
+func (p *DbPool) aggregate(expr string) *DbPool {
+	q := p.clone()
+	q.selectCondition = []string{expr}
+	q.orderCondition = nil
+	q.limit = 0
+	q.limitSet = false
+	return q
+}
+
+// scalar 查询单个聚合值到 dest,结果为 NULL 时 dest 为零值
+func (p *DbPool) scalar(ctx context.Context, expr string, dest interface{}) error {
+	q := p.aggregate(expr)
+	GetSql, args, err := q.sql(ctx)
+	if err != nil {
+		return err
+	}
+	return q.FetchOneIntoCtx(ctx, dest, GetSql, args...)
+}
+
+// Sum 求和,没有数据时返回0
+func (p *DbPool) Sum(ctx context.Context, column string) (float64, error) {
+	var sum float64
+	err := p.scalar(ctx, "SUM("+quoteIdent(p.dialect(), column)+")", &sum)
+	return sum, err
+}
+
+// Avg 平均值,没有数据时返回0
+func (p *DbPool) Avg(ctx context.Context, column string) (float64, error) {
+	var avg float64
+	err := p.scalar(ctx, "AVG("+quoteIdent(p.dialect(), column)+")", &avg)
+	return avg, err
+}
+
+// Min 最小值写入 dest（基础类型指针或 time.Time 指针）,没有数据时为零值
+func (p *DbPool) Min(ctx context.Context, column string, dest interface{}) error {
+	return p.scalar(ctx, "MIN("+quoteIdent(p.dialect(), column)+")", dest)
+}
+
+// Max 最大值写入 dest,没有数据时为零值
+func (p *DbPool) Max(ctx context.Context, column string, dest interface{}) error {
+	return p.scalar(ctx, "MAX("+quoteIdent(p.dialect(), column)+")", dest)
+}
+
+// Exists 是否存在满足条件的数据
+func (p *DbPool) Exists(ctx context.Context) (bool, error) {
+	q := p.aggregate("1")
+	q.limit = 1
+	q.limitSet = true
+	GetSql, args, err := q.sql(ctx)
+	if err != nil {
+		return false, err
+	}
+	var one int
+	err = q.FetchOneIntoCtx(ctx, &one, GetSql, args...)
+	if errors.Is(err, ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Pluck 查询单列写入 dest（slice指针）,保留排序,未显式调用 Limit 时读取全部数据
+func (p *DbPool) Pluck(ctx context.Context, column string, dest interface{}) error {
+	q := p.clone()
+	q.selectCondition = []string{quoteIdent(p.dialect(), column)}
+	if !q.limitSet {
+		q.limit = 0
+	}
+	return q.AllIntoCtx(ctx, dest)
+}
+
+// countGrouped 分组或去重查询的行数,以子查询统计
+func (p *DbPool) countGrouped(ctx context.Context) (int, error) {
+	inner := p.clone()
+	inner.orderCondition = nil
+	inner.limit = 0
+	inner.limitSet = false
+	outer := p.session()
+	outer.fromSub = inner
+	outer.fromAlias = "gosf_count"
+	outer.selectCondition = []string{"count(*) AS count"}
+	outer.limit = 0
+	GetSql, args, err := outer.sql(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	if err = outer.FetchOneIntoCtx(ctx, &count, GetSql, args...); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GroupCount 按 column 分组计数,dest 为 map 指针,如 *map[int]int
+func (p *DbPool) GroupCount(ctx context.Context, column string, dest interface{}) error {
+	return p.GroupAggregate(ctx, column, "COUNT(*)", dest)
+}
+
+// GroupSum 按 groupColumn 分组对 column 求和
+func (p *DbPool) GroupSum(ctx context.Context, groupColumn, column string, dest interface{}) error {
+	return p.GroupAggregate(ctx, groupColumn, "SUM("+quoteIdent(p.dialect(), column)+")", dest)
+}
+
+// GroupAvg 按 groupColumn 分组求 column 平均值
+func (p *DbPool) GroupAvg(ctx context.Context, groupColumn, column string, dest interface{}) error {
+	return p.GroupAggregate(ctx, groupColumn, "AVG("+quoteIdent(p.dialect(), column)+")", dest)
+}
+
+// GroupMin 按 groupColumn 分组求 column 最小值
+func (p *DbPool) GroupMin(ctx context.Context, groupColumn, column string, dest interface{}) error {
+	return p.GroupAggregate(ctx, groupColumn, "MIN("+quoteIdent(p.dialect(), column)+")", dest)
+}
+
+// GroupMax 按 groupColumn 分组求 column 最大值
+func (p *DbPool) GroupMax(ctx context.Context, groupColumn, column string, dest interface{}) error {
+	return p.GroupAggregate(ctx, groupColumn, "MAX("+quoteIdent(p.dialect(), column)+")", dest)
+}
+
+// GroupAggregate 按 groupColumn 分组计算聚合表达式 expr,结果写入 dest（map指针）,已有的 GroupBy 被替换
+func (p *DbPool) GroupAggregate(ctx context.Context, groupColumn, expr string, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Map {
+		return errors.New("gosf: group destination must be a pointer to map")
+	}
+	group := quoteIdent(p.dialect(), groupColumn)
+	q := p.aggregate(group)
+	q.selectCondition = append(q.selectCondition, expr)
+	q.groupCondition = []string{group}
+	GetSql, args, err := q.sql(ctx)
+	if err != nil {
+		return err
+	}
+	rows, err := q.queryRows(ctx, GetSql, args...)
+	if err != nil {
+		return err
+	}
+	defer closeRows(rows)
+
+	m := v.Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+	keyType, valueType := m.Type().Key(), m.Type().Elem()
+	var rawKey, rawValue interface{}
+	for rows.Next() {
+		if err = rows.Scan(&rawKey, &rawValue); err != nil {
+			return fmt.Errorf("rows scan error: %w", err)
+		}
+		key := reflect.New(keyType).Elem()
+		if err = assignValue(key, rawKey, false, p.location()); err != nil {
+			return fmt.Errorf("scan group key: %w", err)
+		}
+		value := reflect.New(valueType).Elem()
+		if err = assignValue(value, rawValue, false, p.location()); err != nil {
+			return fmt.Errorf("scan group value: %w", err)
+		}
+		m.SetMapIndex(key, value)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows iterate error: %w", err)
+	}
+	return nil
+}
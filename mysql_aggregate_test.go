@@ -0,0 +1,44 @@
+package gosf
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSqliteAggregates(t *testing.T) {
+	db := openSqlite(t)
+	ctx := context.Background()
+	rows := []map[string]interface{}{{"name": "a", "hits": 1}, {"name": "b", "hits": 3}, {"name": "c", "hits": 3}}
+	if _, err := db.Table("user").BatchInsert(rows); err != nil {
+		t.Fatal(err)
+	}
+	q := db.Table("user").Where(Gt("hits", 0))
+	if sum, err := q.Sum(ctx, "hits"); err != nil || sum != 7 {
+		t.Errorf("sum: %v %v", sum, err)
+	}
+	if sum, err := q.Sum(ctx, "hits*id"); err != nil || sum != 1+6+9 {
+		t.Errorf("expression sum: %v %v", sum, err)
+	}
+	if sum, err := q.Where("name=?", "z").Sum(ctx, "hits"); err != nil || sum != 0 {
+		t.Errorf("empty sum: %v %v", sum, err)
+	}
+	var max int
+	if err := q.Max(ctx, "hits", &max); err != nil || max != 3 {
+		t.Errorf("max: %v %v", max, err)
+	}
+	if ok, err := q.Where("name=?", "z").Exists(ctx); err != nil || ok {
+		t.Errorf("exists: %v %v", ok, err)
+	}
+	var names []string
+	if err := q.OrderBy("name DESC").Pluck(ctx, "name", &names); err != nil || !reflect.DeepEqual(names, []string{"c", "b", "a"}) {
+		t.Errorf("pluck: %v %v", names, err)
+	}
+	var counts map[int]int
+	if err := q.GroupCount(ctx, "hits", &counts); err != nil || !reflect.DeepEqual(counts, map[int]int{1: 1, 3: 2}) {
+		t.Errorf("group count: %v %v", counts, err)
+	}
+	if n, err := q.GroupBy("hits").CountCtx(ctx); err != nil || n != 2 {
+		t.Errorf("grouped count: %v %v", n, err)
+	}
+}
@@ -0,0 +1,297 @@
+package gosf
+
+/**
+批量插入
+列按名称排序,每行的列必须相同,缺少列时可以用 NULL 或列默认值补齐
+result, err := db.Table("log").BatchInsertCtx(ctx, rows, BatchOptions{Fill: FillNull, ChunkSize: 500})
+result.Affected                      // 总影响行数
+result.Chunks[0].FirstId / LastId    // 每批插入的自增ID范围
+超过 max_allowed_packet 或占位符数量上限时自动拆分为多条SQL,多批时在事务中执行
+MySQL 默认读取 @@max_allowed_packet,也可以通过 MaxPacket 指定
+自增ID范围要求 innodb_autoinc_lock_mode 不为2,或同一时间没有其他插入,PostgreSQL 不返回自增ID
+*/
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BatchFill 批量插入时行缺少列的处理方式
+type BatchFill int
+
+const (
+	FillError   BatchFill = iota // 返回错误
+	FillNull                     // 以 NULL 补齐
+	FillDefault                  // 以列默认值补齐,SQLite 不支持
+)
+
+// 默认参数
+const (
+	defaultMaxPacket      = 4 << 20 // 无法读取 max_allowed_packet 时使用4M
+	packetReserve         = 1024    // 数据包预留字节
+	maxPlaceholders       = 65535   // MySQL、PostgreSQL 单条SQL占位符上限
+	maxSqlitePlaceholders = 32766   // SQLite 单条SQL占位符上限
+)
+
+// BatchOptions 批量插入选项
+type BatchOptions struct {
+	Fill      BatchFill // 缺少列时的处理方式,默认返回错误
+	ChunkSize int       // 每批最多行数,为0时只按数据包大小与占位符数量拆分
+	MaxPacket int       // 单条SQL最大字节数,为0时 MySQL 读取 max_allowed_packet
+}
+
+// BatchResult 批量插入结果
+type BatchResult struct {
+	Affected int          // 总影响行数
+	Chunks   []BatchChunk // 每批的结果,按执行顺序
+}
+
+// BatchChunk 单批插入结果
+type BatchChunk struct {
+	Rows     int   // 本批行数
+	Affected int   // 本批影响的行数
+	FirstId  int64 // 本批第一条自增ID,不支持时为0
+	LastId   int64 // 本批最后一条自增ID,不支持时为0
+}
+
+// BatchInsertCtx 批量插入,返回每批的影响行数与自增ID范围
+func (p *DbPool) BatchInsertCtx(ctx context.Context, rows []map[string]interface{}, opts BatchOptions) (*BatchResult, error) {
+	return p.batchInsert(ctx, InsertDefault, rows, nil, opts)
+}
+
+// batchInsert 批量插入,updateColumns 为空且为 InsertOrUpdate 时更新除创建时间外的全部列
+func (p *DbPool) batchInsert(ctx context.Context, mode InsertMode, rows []map[string]interface{}, updateColumns []string, opts BatchOptions) (*BatchResult, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("gosf: batch insert without rows")
+	}
+	if tableOpts, ok := p.tableOptions(); ok && tableOpts.Timestamps {
+		stamped := make([]map[string]interface{}, len(rows))
+		for i, row := range rows {
+			stamped[i] = p.withTimestamps(row, true)
+		}
+		rows = stamped
+	}
+	columns, err := p.batchColumns(rows, opts.Fill)
+	if err != nil {
+		return nil, err
+	}
+	if mode == InsertOrUpdate {
+		if len(updateColumns) == 0 {
+			updateColumns = p.withoutCreatedAt(columns)
+		} else {
+			updateColumns = p.withUpdatedAt(updateColumns)
+		}
+	}
+
+	// 拼接公共的SQL开头与结尾,用于估算每批大小
+	head := p.writer(ctx)
+	if err = p.writeInsert(head, mode, columns); err != nil {
+		return nil, err
+	}
+	head.WriteString(" VALUES ")
+	tail := p.writer(ctx)
+	if err = p.writeConflict(tail, mode, updateColumns); err != nil {
+		return nil, err
+	}
+	chunks, err := p.batchChunks(ctx, rows, columns, head.Len()+tail.Len(), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BatchResult{}
+	run := func(db *DbPool) error {
+		for _, chunk := range chunks {
+			item, err := db.insertChunk(ctx, mode, columns, chunk, updateColumns, opts.Fill)
+			if err != nil {
+				return err
+			}
+			result.Affected += item.Affected
+			result.Chunks = append(result.Chunks, item)
+		}
+		return nil
+	}
+	// 多批时在事务中执行,已在事务中时直接执行
+	if len(chunks) == 1 || p.tx != nil {
+		err = run(p)
+	} else {
+		err = p.Transaction(ctx, func(tx *DbPool) error {
+			q := p.clone()
+			q.tx, q.txDepth = tx.tx, tx.txDepth
+			return run(q)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// batchColumns 按名称排序的列,校验每行的列是否相同
+func (p *DbPool) batchColumns(rows []map[string]interface{}, fill BatchFill) ([]string, error) {
+	if fill == FillError {
+		columns := sortedKeys(rows[0])
+		if len(columns) == 0 {
+			return nil, errors.New("gosf: batch insert without columns")
+		}
+		for i, row := range rows[1:] {
+			if len(row) != len(columns) {
+				return nil, fmt.Errorf("gosf: batch insert row %d has %d columns, want %d", i+1, len(row), len(columns))
+			}
+			for _, column := range columns {
+				if _, ok := row[column]; !ok {
+					return nil, fmt.Errorf("gosf: batch insert row %d missing column %q", i+1, column)
+				}
+			}
+		}
+		return columns, nil
+	}
+	if fill == FillDefault && p.dialect() == SqliteDialect {
+		return nil, errors.New("gosf: sqlite does not support DEFAULT in batch insert")
+	}
+	union := make(map[string]interface{})
+	for _, row := range rows {
+		for column := range row {
+			union[column] = nil
+		}
+	}
+	if len(union) == 0 {
+		return nil, errors.New("gosf: batch insert without columns")
+	}
+	return sortedKeys(union), nil
+}
+
+// batchChunks 按行数、数据包大小与占位符数量拆分
+func (p *DbPool) batchChunks(ctx context.Context, rows []map[string]interface{}, columns []string, fixed int, opts BatchOptions) ([][]map[string]interface{}, error) {
+	maxRows := maxPlaceholders / len(columns)
+	if p.dialect() == SqliteDialect {
+		maxRows = maxSqlitePlaceholders / len(columns)
+	}
+	if opts.ChunkSize > 0 && opts.ChunkSize < maxRows {
+		maxRows = opts.ChunkSize
+	}
+	if maxRows == 0 {
+		return nil, fmt.Errorf("gosf: batch insert with too many columns %d", len(columns))
+	}
+	maxPacket := opts.MaxPacket
+	if maxPacket <= 0 {
+		maxPacket = p.maxPacket(ctx)
+	}
+
+	var chunks [][]map[string]interface{}
+	start, size := 0, fixed
+	for i, row := range rows {
+		rowSize := 3 // (),
+		for _, column := range columns {
+			rowSize += 2 + argSize(row[column])
+		}
+		if maxPacket > 0 && fixed+rowSize > maxPacket-packetReserve {
+			return nil, fmt.Errorf("gosf: batch insert row %d exceeds max packet %d", i, maxPacket)
+		}
+		if i > start && (i-start >= maxRows || maxPacket > 0 && size+rowSize > maxPacket-packetReserve) {
+			chunks = append(chunks, rows[start:i])
+			start, size = i, fixed
+		}
+		size += rowSize
+	}
+	return append(chunks, rows[start:]), nil
+}
+
+// maxPacket MySQL 的 max_allowed_packet,读取一次后缓存,其他数据库不限制
+func (p *DbPool) maxPacket(ctx context.Context) int {
+	if p.dialect() != MysqlDialect {
+		return 0
+	}
+	if p.cluster != nil {
+		if n := p.cluster.maxPacket.Load(); n > 0 {
+			return int(n)
+		}
+	}
+	var n int64
+	if err := p.FetchOneIntoCtx(ctx, &n, "SELECT @@max_allowed_packet"); err != nil || n <= 0 {
+		return defaultMaxPacket
+	}
+	if p.cluster != nil {
+		p.cluster.maxPacket.Store(n)
+	}
+	return int(n)
+}
+
+// argSize 估算参数占用的字节数
+func argSize(v interface{}) int {
+	switch x := v.(type) {
+	case nil:
+		return 1
+	case string:
+		return len(x) + 9
+	case []byte:
+		return len(x) + 9
+	case time.Time:
+		return 12
+	case driver.Valuer:
+		if value, err := x.Value(); err == nil {
+			if _, ok := value.(driver.Valuer); !ok {
+				return argSize(value)
+			}
+		}
+		return 64
+	case fmt.Stringer:
+		return len(x.String()) + 9
+	}
+	return 16
+}
+
+// insertChunk 执行单批插入
+func (p *DbPool) insertChunk(ctx context.Context, mode InsertMode, columns []string, rows []map[string]interface{}, updateColumns []string, fill BatchFill) (BatchChunk, error) {
+	w := p.writer(ctx)
+	if err := p.writeInsert(w, mode, columns); err != nil {
+		return BatchChunk{}, err
+	}
+	w.WriteString(" VALUES ")
+	for i, row := range rows {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString("(")
+		for j, column := range columns {
+			if j > 0 {
+				w.WriteString(",")
+			}
+			value, ok := row[column]
+			if !ok && fill == FillDefault {
+				w.WriteString("DEFAULT")
+				continue
+			}
+			w.writeArg(value)
+		}
+		w.WriteString(")")
+	}
+	if err := p.writeConflict(w, mode, updateColumns); err != nil {
+		return BatchChunk{}, err
+	}
+	ret, err := p.exec(ctx, w.String(), w.args...)
+	if err != nil {
+		return BatchChunk{}, err
+	}
+	affected, err := ret.RowsAffected()
+	if err != nil {
+		return BatchChunk{}, err
+	}
+	chunk := BatchChunk{Rows: len(rows), Affected: int(affected)}
+	if mode != InsertDefault || !w.sqlDialect().LastInsertId() {
+		return chunk, nil
+	}
+	id, err := ret.LastInsertId()
+	if err != nil || id == 0 {
+		return chunk, nil
+	}
+	// MySQL 返回本批第一条ID,SQLite 返回最后一条ID
+	n := int64(len(rows))
+	if w.sqlDialect() == SqliteDialect {
+		chunk.FirstId, chunk.LastId = id-n+1, id
+	} else {
+		chunk.FirstId, chunk.LastId = id, id+n-1
+	}
+	return chunk, nil
+}
@@ -0,0 +1,39 @@
+package gosf
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestSqliteBatchInsert(t *testing.T) {
+	db := openSqlite(t)
+	ctx := context.Background()
+
+	mixed := []map[string]interface{}{{"name": "a", "hits": 1}, {"name": "b"}}
+	if _, err := db.Table("user").BatchInsertCtx(ctx, mixed, BatchOptions{}); err == nil {
+		t.Fatal("expected error for rows with different columns")
+	}
+	if _, err := db.Table("user").BatchInsertCtx(ctx, mixed, BatchOptions{Fill: FillDefault}); err == nil {
+		t.Fatal("expected error for DEFAULT on sqlite")
+	}
+
+	var rows []map[string]interface{}
+	for i := 0; i < 5; i++ {
+		rows = append(rows, map[string]interface{}{"name": fmt.Sprintf("u%d", i), "hits": i})
+	}
+	result, err := db.Table("user").BatchInsertCtx(ctx, rows, BatchOptions{ChunkSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Affected != 5 || len(result.Chunks) != 3 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if c := result.Chunks[1]; c.Rows != 2 || c.FirstId != 3 || c.LastId != 4 {
+		t.Fatalf("unexpected chunk %+v", c)
+	}
+	var hits int64
+	if err = db.Table("user").Where("id=?", 5).Select("hits").GetInto(&hits); err != nil || hits != 4 {
+		t.Fatalf("hits=%d err=%v", hits, err)
+	}
+}
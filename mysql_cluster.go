@@ -0,0 +1,196 @@
+package gosf
+
+/**
+读写分离,一个命名数据库可以配置一个主库与多个从库
+{
+	"mysql": {
+		"base": {
+			"dsn": "user:pwd@tcp(primary:3306)/base",
+			"replicas": [
+				"user:pwd@tcp(replica1:3306)/base",
+				{"dsn": "user:pwd@tcp(replica2:3306)/base", "max_open": 50}
+			],
+			"health_check_interval": "5s"
+		}
+	}
+}
+Get、All、Count、FetchAll 等读操作轮询分配到健康的从库,写操作与事务使用主库
+写后立即读取时使用 UsePrimary() 强制走主库
+db.Table("user").UsePrimary().Where("id=?", id).Get()
+从库健康状态变化写入日志,未设置时使用标准输出
+mysql.SetLogger(app.Logger)
+*/
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// 从库健康检测默认间隔
+const defaultHealthCheckInterval = 5 * time.Second
+
+// dbCluster 命名数据库,一个主库与若干从库
+type dbCluster struct {
+	name      string
+	primary   *sql.DB
+	replicas  []*replica
+	next      atomic.Uint32 // 轮询计数
+	stop      chan struct{}
+	stopOnce  sync.Once
+	hooks     *queryHooks             // 所属 Mysql 实例的查询钩子
+	logger    *atomic.Pointer[Logger] // 所属 Mysql 实例的日志
+	dialect   Dialect
+	loc       *time.Location // 解析时间文本使用的时区,为空时使用 time.Local
+	tables    tableRegistry  // 数据表选项与全局条件
+	maxPacket atomic.Int64   // 缓存的 max_allowed_packet
+}
+
+// replica 从库
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// newCluster 创建命名数据库,有从库时启动健康检测
+func newCluster(name string, primary *sql.DB, replicas []*sql.DB, interval time.Duration, logger *atomic.Pointer[Logger]) *dbCluster {
+	c := &dbCluster{name: name, primary: primary, stop: make(chan struct{}), logger: logger}
+	for _, db := range replicas {
+		r := &replica{db: db}
+		r.healthy.Store(true)
+		c.replicas = append(c.replicas, r)
+	}
+	if len(c.replicas) > 0 {
+		if interval <= 0 {
+			interval = defaultHealthCheckInterval
+		}
+		go c.healthCheck(interval)
+	}
+	return c
+}
+
+// reader 轮询选择健康的从库,没有可用从库时返回 nil
+func (c *dbCluster) reader() *replica {
+	n := len(c.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := c.next.Add(1)
+	for i := 0; i < n; i++ {
+		r := c.replicas[(int(start)+i)%n]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// healthCheck 定时检测从库,失败的从库移出轮询,恢复后重新加入
+func (c *dbCluster) healthCheck(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			for _, r := range c.replicas {
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				err := r.db.PingContext(ctx)
+				cancel()
+				if err != nil {
+					c.markUnhealthy(r, err)
+				} else if !r.healthy.Swap(true) {
+					c.log(false, "mysql replica recovered", c.name, c.replicaIndex(r))
+				}
+			}
+		}
+	}
+}
+
+// markUnhealthy 从库移出轮询,状态变化时记录日志
+func (c *dbCluster) markUnhealthy(r *replica, err error) {
+	if r.healthy.Swap(false) {
+		c.log(true, "mysql replica unhealthy", c.name, c.replicaIndex(r), err)
+	}
+}
+
+// replicaIndex 从库在配置中的序号
+func (c *dbCluster) replicaIndex(r *replica) int {
+	for i, item := range c.replicas {
+		if item == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// log 写入所属 Mysql 实例的日志,未设置时使用标准输出
+func (c *dbCluster) log(isError bool, v ...any) {
+	var logger *Logger
+	if c.logger != nil {
+		logger = c.logger.Load()
+	}
+	switch {
+	case logger == nil:
+		fmt.Println(v...)
+	case isError:
+		logger.Error(v...)
+	default:
+		logger.Info(v...)
+	}
+}
+
+// close 停止健康检测并关闭主从连接
+func (c *dbCluster) close() error {
+	c.stopOnce.Do(func() { close(c.stop) })
+	err := c.primary.Close()
+	for _, r := range c.replicas {
+		if rErr := r.db.Close(); rErr != nil && err == nil {
+			err = rErr
+		}
+	}
+	return err
+}
+
+// isConnError 是否为连接层错误,从库出现此类错误时移出轮询并改用主库重试
+func isConnError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// location 解析时间文本使用的时区,按配置打开时为DSN的 loc,否则为 time.Local
+func (p *DbPool) location() *time.Location {
+	if p.cluster != nil && p.cluster.loc != nil {
+		return p.cluster.loc
+	}
+	return time.Local
+}
+
+// UsePrimary 读操作强制使用主库,用于写后立即读取
+func (p *DbPool) UsePrimary() *DbPool {
+	q := p.clone()
+	q.usePrimary = true
+	return q
+}
+
+// readConn 获取读连接,事务中或强制主库时使用主库,否则使用健康的从库
+func (p *DbPool) readConn() (sqlConn, *replica) {
+	if p.tx != nil || p.usePrimary || p.cluster == nil {
+		return p.conn(), nil
+	}
+	if r := p.cluster.reader(); r != nil {
+		return r.db, r
+	}
+	return p.pool, nil
+}
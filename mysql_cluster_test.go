@@ -0,0 +1,88 @@
+package gosf_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/oyjz/gosf"
+	"github.com/oyjz/gosf/dbtest"
+)
+
+const userQuery = "SELECT * FROM `user` WHERE id=? LIMIT 0, 1"
+
+func expectUser(mock *dbtest.Mock, name string) {
+	mock.ExpectQuery(userQuery).WithArgs(1).WillReturnRows(dbtest.NewRows("id", "name").AddRow(1, name))
+}
+
+func getUser(t *testing.T, db *gosf.DbPool) string {
+	t.Helper()
+	row, err := db.Table("user").Where("id=?", 1).GetCtx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, _ := row["name"].(string)
+	return name
+}
+
+func TestClusterRoundRobin(t *testing.T) {
+	primary, r1, r2 := dbtest.New(), dbtest.New(), dbtest.New()
+	m := &gosf.Mysql{}
+	if err := m.Register("base", primary.DB(), r1.DB(), r2.DB()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	db := m.MustDB("base")
+
+	expectUser(r1, "r1")
+	expectUser(r2, "r2")
+	expectUser(primary, "primary")
+	seen := map[string]bool{getUser(t, db): true, getUser(t, db): true}
+	if !seen["r1"] || !seen["r2"] {
+		t.Errorf("reads should alternate between replicas, got %v", seen)
+	}
+	if name := getUser(t, db.UsePrimary()); name != "primary" {
+		t.Errorf("UsePrimary read from %s", name)
+	}
+	for _, mock := range []*dbtest.Mock{primary, r1, r2} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestClusterReplicaFallback(t *testing.T) {
+	primary, replica := dbtest.New(), dbtest.New()
+	m := &gosf.Mysql{}
+	if err := m.Register("base", primary.DB(), replica.DB()); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	var buf bytes.Buffer
+	m.SetLogger(&gosf.Logger{ErrorLogger: log.New(&buf, "", 0)})
+	db := m.MustDB("base")
+
+	// database/sql 遇到 ErrBadConn 会换连接重试,每次都返回连接错误
+	for i := 0; i < 3; i++ {
+		replica.ExpectQuery(userQuery).WillReturnError(driver.ErrBadConn)
+	}
+	expectUser(primary, "primary")
+	expectUser(primary, "primary")
+	if name := getUser(t, db); name != "primary" {
+		t.Errorf("expected fallback to primary, got %s", name)
+	}
+	// 从库已标记为不健康,后续读操作直接使用主库
+	if name := getUser(t, db); name != "primary" {
+		t.Errorf("unhealthy replica should be skipped, got %s", name)
+	}
+	// 状态变化只记录一次
+	if got := buf.String(); strings.Count(got, "mysql replica unhealthy base 0") != 1 {
+		t.Errorf("unexpected log %q", got)
+	}
+	if err := primary.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,208 @@
+package gosf
+
+/**
+数据库配置,mysql 下每个数据库可以是DSN字符串,也可以是带连接池参数的对象
+{
+	"mysql": {
+		"base": "user:pwd@tcp(127.0.0.1:3306)/base?charset=utf8mb4",
+		"report": {
+			"dsn": "user:pwd@tcp(127.0.0.1:3306)/report?charset=utf8mb4",
+			"max_open": 200,
+			"max_idle": 20,
+			"max_lifetime": "5m",
+			"max_idle_time": "1m",
+			"connect_timeout": "3s",
+			"read_timeout": "30s",
+			"write_timeout": 30
+		}
+	}
+}
+时间可以是 "5m"、"30s" 形式的字符串,也可以是以秒为单位的数字
+*/
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// 连接池默认参数
+const (
+	defaultMaxOpenConns    = 1000
+	defaultMaxIdleConns    = 10
+	defaultConnMaxLifetime = 5 * time.Second
+	defaultPingTimeout     = 5 * time.Second
+)
+
+// MysqlOptions 单个数据库连接配置
+type MysqlOptions struct {
+	Dsn            string        // 连接地址
+	MaxOpen        int           // 最大连接数,不能大于数据库设置的最大链接数,默认1000
+	MaxIdle        int           // 最大空闲连接数,小于最大连接数,默认10
+	MaxLifetime    time.Duration // 连接最长存活时间,不能大于数据库设置的超时时间,默认5秒
+	MaxIdleTime    time.Duration // 空闲连接最长存活时间,默认不限制
+	ConnectTimeout time.Duration // 建立连接超时时间,同时用于启动时的连通性检测
+	ReadTimeout    time.Duration // 读超时
+	WriteTimeout   time.Duration // 写超时
+
+	Replicas            []MysqlOptions // 从库,未设置的连接池参数继承主库
+	HealthCheckInterval time.Duration  // 从库健康检测间隔,默认5秒
+}
+
+// configDuration 配置中的时间,支持 "5s" 字符串或秒数
+type configDuration time.Duration
+
+func (d *configDuration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case nil:
+		*d = 0
+	case float64:
+		*d = configDuration(v * float64(time.Second))
+	case string:
+		duration, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = configDuration(duration)
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	return nil
+}
+
+// UnmarshalJSON 支持DSN字符串或配置对象
+func (o *MysqlOptions) UnmarshalJSON(data []byte) error {
+	var dsn string
+	if err := json.Unmarshal(data, &dsn); err == nil {
+		*o = MysqlOptions{Dsn: dsn}
+		return nil
+	}
+	var item struct {
+		Dsn            string         `json:"dsn"`
+		MaxOpen        int            `json:"max_open"`
+		MaxIdle        int            `json:"max_idle"`
+		MaxLifetime    configDuration `json:"max_lifetime"`
+		MaxIdleTime    configDuration `json:"max_idle_time"`
+		ConnectTimeout configDuration `json:"connect_timeout"`
+		ReadTimeout    configDuration `json:"read_timeout"`
+		WriteTimeout   configDuration `json:"write_timeout"`
+
+		Replicas            []MysqlOptions `json:"replicas"`
+		HealthCheckInterval configDuration `json:"health_check_interval"`
+	}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+	*o = MysqlOptions{
+		Dsn:            item.Dsn,
+		MaxOpen:        item.MaxOpen,
+		MaxIdle:        item.MaxIdle,
+		MaxLifetime:    time.Duration(item.MaxLifetime),
+		MaxIdleTime:    time.Duration(item.MaxIdleTime),
+		ConnectTimeout: time.Duration(item.ConnectTimeout),
+		ReadTimeout:    time.Duration(item.ReadTimeout),
+		WriteTimeout:   time.Duration(item.WriteTimeout),
+
+		Replicas:            item.Replicas,
+		HealthCheckInterval: time.Duration(item.HealthCheckInterval),
+	}
+	return nil
+}
+
+// inherit 从库未设置的连接池参数继承主库配置
+func (o MysqlOptions) inherit(primary MysqlOptions) MysqlOptions {
+	if o.MaxOpen == 0 {
+		o.MaxOpen = primary.MaxOpen
+	}
+	if o.MaxIdle == 0 {
+		o.MaxIdle = primary.MaxIdle
+	}
+	if o.MaxLifetime == 0 {
+		o.MaxLifetime = primary.MaxLifetime
+	}
+	if o.MaxIdleTime == 0 {
+		o.MaxIdleTime = primary.MaxIdleTime
+	}
+	if o.ConnectTimeout == 0 {
+		o.ConnectTimeout = primary.ConnectTimeout
+	}
+	if o.ReadTimeout == 0 {
+		o.ReadTimeout = primary.ReadTimeout
+	}
+	if o.WriteTimeout == 0 {
+		o.WriteTimeout = primary.WriteTimeout
+	}
+	o.Replicas = nil
+	return o
+}
+
+// dsnLocation DSN 中 loc 参数指定的时区,驱动默认为 UTC
+func dsnLocation(dsn string) *time.Location {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil
+	}
+	return cfg.Loc
+}
+
+// openMysqlDB 按配置打开连接池并检测连通性
+func openMysqlDB(name string, opts MysqlOptions) (*sql.DB, error) {
+	if opts.Dsn == "" {
+		return nil, fmt.Errorf("gosf: mysql %q: dsn is empty", name)
+	}
+	cfg, err := mysql.ParseDSN(opts.Dsn)
+	if err != nil {
+		return nil, fmt.Errorf("gosf: mysql %q: invalid dsn: %w", name, err)
+	}
+	if opts.ConnectTimeout > 0 {
+		cfg.Timeout = opts.ConnectTimeout
+	}
+	if opts.ReadTimeout > 0 {
+		cfg.ReadTimeout = opts.ReadTimeout
+	}
+	if opts.WriteTimeout > 0 {
+		cfg.WriteTimeout = opts.WriteTimeout
+	}
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("gosf: mysql %q: %w", name, err)
+	}
+	db := sql.OpenDB(connector)
+
+	maxOpen, maxIdle, maxLifetime := defaultMaxOpenConns, defaultMaxIdleConns, defaultConnMaxLifetime
+	if opts.MaxOpen > 0 {
+		maxOpen = opts.MaxOpen
+	}
+	if opts.MaxIdle > 0 {
+		maxIdle = opts.MaxIdle
+	}
+	if opts.MaxLifetime > 0 {
+		maxLifetime = opts.MaxLifetime
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(maxLifetime)
+	if opts.MaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(opts.MaxIdleTime)
+	}
+
+	// 启动时检测连通性
+	pingTimeout := defaultPingTimeout
+	if opts.ConnectTimeout > 0 {
+		pingTimeout = opts.ConnectTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("gosf: mysql %q (%s@%s) unreachable: %w", name, cfg.User, cfg.Addr, err)
+	}
+	return db, nil
+}
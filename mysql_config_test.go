@@ -0,0 +1,63 @@
+package gosf
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMysqlOptionsUnmarshal(t *testing.T) {
+	cases := []struct {
+		name string
+		json string
+		want MysqlOptions
+		err  bool
+	}{
+		{"dsn", `"user:pwd@tcp(127.0.0.1:3306)/base"`, MysqlOptions{Dsn: "user:pwd@tcp(127.0.0.1:3306)/base"}, false},
+		{
+			"object",
+			`{"dsn":"d","max_open":200,"max_idle":20,"max_lifetime":"5m","max_idle_time":"1m30s","connect_timeout":"3s","read_timeout":null}`,
+			MysqlOptions{Dsn: "d", MaxOpen: 200, MaxIdle: 20, MaxLifetime: 5 * time.Minute, MaxIdleTime: 90 * time.Second, ConnectTimeout: 3 * time.Second},
+			false,
+		},
+		{"seconds", `{"dsn":"d","write_timeout":30,"read_timeout":1.5}`, MysqlOptions{Dsn: "d", WriteTimeout: 30 * time.Second, ReadTimeout: 1500 * time.Millisecond}, false},
+		{
+			"replicas",
+			`{"dsn":"p","health_check_interval":"10s","replicas":["r1",{"dsn":"r2","max_open":5}]}`,
+			MysqlOptions{Dsn: "p", HealthCheckInterval: 10 * time.Second, Replicas: []MysqlOptions{{Dsn: "r1"}, {Dsn: "r2", MaxOpen: 5}}},
+			false,
+		},
+		{"bad duration", `{"dsn":"d","max_lifetime":"5 minutes"}`, MysqlOptions{}, true},
+		{"bad duration type", `{"dsn":"d","max_lifetime":true}`, MysqlOptions{}, true},
+		{"bad type", `123`, MysqlOptions{}, true},
+	}
+	for _, c := range cases {
+		var got MysqlOptions
+		err := json.Unmarshal([]byte(c.json), &got)
+		if (err != nil) != c.err {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if !c.err && !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: got %+v\nwant %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestMysqlOptionsInherit(t *testing.T) {
+	var opts MysqlOptions
+	data := `{"dsn":"p","max_open":100,"max_idle":10,"read_timeout":"2s","write_timeout":3,"replicas":["r1",{"dsn":"r2","max_open":5,"read_timeout":1}]}`
+	if err := json.Unmarshal([]byte(data), &opts); err != nil {
+		t.Fatal(err)
+	}
+	want := []MysqlOptions{
+		{Dsn: "r1", MaxOpen: 100, MaxIdle: 10, ReadTimeout: 2 * time.Second, WriteTimeout: 3 * time.Second},
+		{Dsn: "r2", MaxOpen: 5, MaxIdle: 10, ReadTimeout: time.Second, WriteTimeout: 3 * time.Second},
+	}
+	for i, replica := range opts.Replicas {
+		if got := replica.inherit(opts); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("replica %d: got %+v\nwant %+v", i, got, want[i])
+		}
+	}
+}
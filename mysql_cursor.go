@@ -0,0 +1,207 @@
+package gosf
+
+/**
+大数据量遍历
+逐行读取,只占用一行数据的内存,fn 返回 ErrStop 时提前结束
+err := db.Table("order").Where("status=?", 1).Each(ctx, func(row map[string]interface{}) error {
+	return writer.Write(row)
+})
+按主键分批读取,每批使用 id > 上一批最大id 查询,避免 LIMIT offset 越往后越慢
+err := db.Table("order").Chunk(ctx, 1000, func(rows []map[string]interface{}) error {
+	return export(rows)
+})
+游标,可随时停止,必须调用 Close 释放连接
+cursor, err := db.Table("order").Cursor(ctx)
+defer cursor.Close()
+for cursor.Next() {
+	var order Order
+	if err := cursor.Scan(&order); err != nil {
+		return err
+	}
+}
+err = cursor.Err()
+未显式调用 Limit 时,Each、Cursor 不使用默认分页,读取全部数据
+*/
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrStop Each、Chunk 的回调返回该错误时停止遍历,Each、Chunk 返回 nil
+var ErrStop = errors.New("gosf: stop iteration")
+
+// Cursor 查询结果游标,逐行读取,使用完毕必须调用 Close 释放连接
+type Cursor struct {
+	rows   *sql.Rows
+	mapper *rowMapper
+	fields map[reflect.Type][]*fieldInfo
+	loc    *time.Location // 解析时间文本使用的时区
+	err    error
+}
+
+// Cursor 执行查询并返回游标,未显式调用 Limit 时读取全部数据
+func (p *DbPool) Cursor(ctx context.Context) (*Cursor, error) {
+	w := p.writer(ctx)
+	p.writeSelect(w, true)
+	if w.err != nil {
+		return nil, w.err
+	}
+	rows, err := p.queryRows(ctx, w.String(), w.args...)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := newRowMapper(rows)
+	if err != nil {
+		closeRows(rows)
+		return nil, err
+	}
+	return &Cursor{rows: rows, mapper: mapper, fields: make(map[reflect.Type][]*fieldInfo), loc: p.location()}, nil
+}
+
+// Next 移动到下一行,没有数据或出错时返回 false 并自动释放连接
+func (c *Cursor) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	if c.rows.Next() {
+		return true
+	}
+	if err := c.rows.Err(); err != nil {
+		c.err = fmt.Errorf("rows iterate error: %w", err)
+	}
+	closeRows(c.rows)
+	return false
+}
+
+// Columns 查询结果列名
+func (c *Cursor) Columns() []string {
+	return c.mapper.columns
+}
+
+// Row 以map形式返回当前行
+func (c *Cursor) Row() (map[string]interface{}, error) {
+	row, err := c.mapper.scan(c.rows)
+	if err != nil {
+		c.err = err
+	}
+	return row, err
+}
+
+// Scan 将当前行扫描到 dest（结构体指针或基础类型指针）
+func (c *Cursor) Scan(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("gosf: scan destination must be a non-nil pointer")
+	}
+	t := v.Elem().Type()
+	fields, ok := c.fields[t]
+	if !ok {
+		var err error
+		if fields, err = columnFields(t, c.mapper.columns); err != nil {
+			return err
+		}
+		c.fields[t] = fields
+	}
+	if err := scanRow(c.rows, v.Elem(), c.mapper.columns, fields, c.loc); err != nil {
+		c.err = err
+		return err
+	}
+	return nil
+}
+
+// Err 遍历过程中的错误
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Close 释放连接,可重复调用
+func (c *Cursor) Close() error {
+	return c.rows.Close()
+}
+
+// Each 逐行遍历查询结果,fn 返回 ErrStop 时停止遍历并返回 nil,返回其他错误时停止并返回该错误
+func (p *DbPool) Each(ctx context.Context, fn func(row map[string]interface{}) error) (err error) {
+	cursor, err := p.Cursor(ctx)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close()
+	for cursor.Next() {
+		row, err := cursor.Row()
+		if err != nil {
+			return err
+		}
+		if err = fn(row); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// Chunk 按主键 id 分批遍历,每批最多 size 条
+func (p *DbPool) Chunk(ctx context.Context, size int, fn func(rows []map[string]interface{}) error) error {
+	return p.ChunkBy(ctx, "id", size, fn)
+}
+
+// ChunkBy 按递增的唯一列 column 分批遍历,每批使用 column > 上一批最大值 查询,查询结果中必须包含该列,列值不能为 NULL
+// fn 返回 ErrStop 时停止遍历并返回 nil
+func (p *DbPool) ChunkBy(ctx context.Context, column string, size int, fn func(rows []map[string]interface{}) error) error {
+	if size <= 0 {
+		return fmt.Errorf("gosf: invalid chunk size %d", size)
+	}
+	// 结果中的键名为列名最后一段
+	key := column
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	key = strings.Trim(key, "`")
+	var last interface{}
+	for {
+		// 复制构造器,不修改原查询条件
+		q := *p
+		q.orderCondition = []string{quoteIdent(p.dialect(), column) + " ASC"}
+		q.page = 1
+		q.limit = size
+		q.limitSet = true
+		if last != nil {
+			if q.whereCondition == nil {
+				q.whereCondition = Gt(column, last)
+			} else {
+				q.whereCondition = And(q.whereCondition, Gt(column, last))
+			}
+		}
+		rows, err := q.AllCtx(ctx)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		next, ok := rows[len(rows)-1][key]
+		if !ok {
+			return fmt.Errorf("gosf: chunk column %q not in result", column)
+		}
+		// NULL 排在最前,整批都是 NULL 时无法确定下一批的起点
+		if next == nil && len(rows) == size {
+			return fmt.Errorf("gosf: chunk column %q is NULL", column)
+		}
+		if err = fn(rows); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+		if len(rows) < size {
+			return nil
+		}
+		last = next
+	}
+}
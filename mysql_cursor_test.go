@@ -0,0 +1,131 @@
+package gosf
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// seedUsers 插入 n 条数据,id 从1开始
+func seedUsers(t *testing.T, db *DbPool, n int) {
+	t.Helper()
+	rows := make([]map[string]interface{}, n)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"name": fmt.Sprintf("u%d", i+1), "hits": i + 1}
+	}
+	if _, err := db.Table("user").BatchInsert(rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertConnFree 连接池只有一个连接,连接未释放时查询会超时
+func assertConnFree(t *testing.T, db *DbPool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := db.Table("user").CountCtx(ctx); err != nil {
+		t.Fatalf("connection not released: %v", err)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	db := openSqlite(t)
+	seedUsers(t, db, 3)
+	ctx := context.Background()
+
+	cursor, err := db.Table("user").OrderBy("id ASC").Cursor(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for cursor.Next() {
+		var user struct {
+			Id   int64  `db:"id"`
+			Name string `db:"name"`
+		}
+		if err = cursor.Scan(&user); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, user.Name)
+	}
+	if err = cursor.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(names) != "[u1 u2 u3]" {
+		t.Errorf("unexpected names %v", names)
+	}
+	// 遍历结束自动释放连接
+	assertConnFree(t, db)
+
+	cursor, err = db.Table("user").Cursor(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cursor.Next() {
+		t.Fatal("expected a row")
+	}
+	if err = cursor.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = cursor.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 提前 Close 释放连接
+	assertConnFree(t, db)
+}
+
+func TestEachStop(t *testing.T) {
+	db := openSqlite(t)
+	seedUsers(t, db, 5)
+	seen := 0
+	err := db.Table("user").Each(context.Background(), func(row map[string]interface{}) error {
+		seen++
+		if seen == 2 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil || seen != 2 {
+		t.Fatalf("seen=%d err=%v", seen, err)
+	}
+	assertConnFree(t, db)
+}
+
+func TestChunkBy(t *testing.T) {
+	db := openSqlite(t)
+	seedUsers(t, db, 7)
+	var sizes []int
+	var ids []interface{}
+	err := db.Table("user").Where("hits>?", 0).Chunk(context.Background(), 3, func(rows []map[string]interface{}) error {
+		sizes = append(sizes, len(rows))
+		for _, row := range rows {
+			ids = append(ids, row["id"])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(sizes) != "[3 3 1]" || fmt.Sprint(ids) != "[1 2 3 4 5 6 7]" {
+		t.Errorf("sizes=%v ids=%v", sizes, ids)
+	}
+}
+
+func TestChunkByNull(t *testing.T) {
+	db := openSqlite(t)
+	if _, err := db.Execute("CREATE TABLE item (id INTEGER PRIMARY KEY, code INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Execute("INSERT INTO item (id, code) VALUES (1, NULL), (2, NULL), (3, NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	err := db.Table("item").ChunkBy(context.Background(), "code", 2, func(rows []map[string]interface{}) error {
+		calls++
+		return nil
+	})
+	if err == nil || calls > 1 {
+		t.Fatalf("expected NULL key error, calls=%d err=%v", calls, err)
+	}
+}
@@ -0,0 +1,263 @@
+package gosf
+
+/**
+SQL方言,负责标识符引号、占位符、分页与 INSERT 冲突处理,Open 打开的数据库使用 MysqlDialect
+注册其他数据库时指定方言,同一套 DbPool 代码可以在测试中使用内存 SQLite
+sqlDB, _ := sql.Open("sqlite3", ":memory:")
+m := &Mysql{}
+_ = m.RegisterWith("test", SqliteDialect, sqlDB)
+db := m.MustDB("test")
+PostgreSQL 唯一键冲突更新时需要用 OnConflict 指定冲突列,且不支持返回自增ID,Insert 返回 0
+db.Table("user_stat").OnConflict("user_id").Upsert(row, "hits")
+只生成SQL时使用 WithDialect 指定方言
+sql, args, _ := Query("user").WithDialect(PostgresDialect).Where("id=?", 1).ToSql()
+FetchAll、Execute 等原始SQL原样执行,占位符需按对应数据库书写
+*/
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// InsertMode 插入方式
+type InsertMode int
+
+const (
+	InsertDefault   InsertMode = iota // 普通插入
+	InsertOrIgnore                    // 唯一键冲突时忽略
+	InsertOrReplace                   // 唯一键冲突时替换
+	InsertOrUpdate                    // 唯一键冲突时更新指定列
+)
+
+// Dialect SQL方言
+type Dialect interface {
+	// Name 方言名称
+	Name() string
+	// Quote 单个标识符加引号
+	Quote(ident string) string
+	// Placeholder 第 n 个参数的占位符,从1开始
+	Placeholder(n int) string
+	// Limit 分页子句,包含前导空格
+	Limit(offset, count int) string
+	// InsertVerb INSERT 语句开头,不支持该插入方式时返回错误
+	InsertVerb(mode InsertMode) (string, error)
+	// OnConflict VALUES 之后的冲突处理子句,conflict 为冲突列,update 为需要更新的列
+	OnConflict(mode InsertMode, conflict, update []string) (string, error)
+	// LastInsertId 是否支持返回自增ID
+	LastInsertId() bool
+}
+
+// 内置方言
+var (
+	MysqlDialect    Dialect = mysqlDialect{}
+	SqliteDialect   Dialect = sqliteDialect{}
+	PostgresDialect Dialect = postgresDialect{}
+)
+
+// mysqlDialect MySQL 方言
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Quote(ident string) string { return "`" + ident + "`" }
+
+func (mysqlDialect) Placeholder(n int) string { return "?" }
+
+func (mysqlDialect) Limit(offset, count int) string {
+	return fmt.Sprintf(" LIMIT %d, %d", offset, count)
+}
+
+func (mysqlDialect) InsertVerb(mode InsertMode) (string, error) {
+	switch mode {
+	case InsertOrIgnore:
+		return "INSERT IGNORE", nil
+	case InsertOrReplace:
+		return "REPLACE", nil
+	}
+	return "INSERT", nil
+}
+
+func (d mysqlDialect) OnConflict(mode InsertMode, conflict, update []string) (string, error) {
+	if mode != InsertOrUpdate || len(update) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	b.WriteString(" ON DUPLICATE KEY UPDATE ")
+	for i, column := range update {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		quoted := quoteIdent(d, column)
+		b.WriteString(quoted + "=VALUES(" + quoted + ")")
+	}
+	return b.String(), nil
+}
+
+func (mysqlDialect) LastInsertId() bool { return true }
+
+// sqliteDialect SQLite 方言,冲突更新需要 SQLite 3.35 及以上
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Quote(ident string) string { return `"` + ident + `"` }
+
+func (sqliteDialect) Placeholder(n int) string { return "?" }
+
+func (sqliteDialect) Limit(offset, count int) string {
+	return fmt.Sprintf(" LIMIT %d OFFSET %d", count, offset)
+}
+
+func (sqliteDialect) InsertVerb(mode InsertMode) (string, error) {
+	switch mode {
+	case InsertOrIgnore:
+		return "INSERT OR IGNORE", nil
+	case InsertOrReplace:
+		return "INSERT OR REPLACE", nil
+	}
+	return "INSERT", nil
+}
+
+func (d sqliteDialect) OnConflict(mode InsertMode, conflict, update []string) (string, error) {
+	if mode != InsertOrUpdate || len(update) == 0 {
+		return "", nil
+	}
+	return onConflictUpdate(d, conflict, update), nil
+}
+
+func (sqliteDialect) LastInsertId() bool { return true }
+
+// postgresDialect PostgreSQL 方言
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Quote(ident string) string { return `"` + ident + `"` }
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+func (postgresDialect) Limit(offset, count int) string {
+	return fmt.Sprintf(" LIMIT %d OFFSET %d", count, offset)
+}
+
+func (postgresDialect) InsertVerb(mode InsertMode) (string, error) {
+	if mode == InsertOrReplace {
+		return "", errors.New("gosf: postgres does not support replace, use Upsert")
+	}
+	return "INSERT", nil
+}
+
+func (d postgresDialect) OnConflict(mode InsertMode, conflict, update []string) (string, error) {
+	switch mode {
+	case InsertOrIgnore:
+		return " ON CONFLICT DO NOTHING", nil
+	case InsertOrUpdate:
+		if len(update) == 0 {
+			return "", nil
+		}
+		if len(conflict) == 0 {
+			return "", errors.New("gosf: postgres upsert requires OnConflict columns")
+		}
+		return onConflictUpdate(d, conflict, update), nil
+	}
+	return "", nil
+}
+
+func (postgresDialect) LastInsertId() bool { return false }
+
+// onConflictUpdate 拼接 ON CONFLICT ("a") DO UPDATE SET "b"=excluded."b"
+func onConflictUpdate(d Dialect, conflict, update []string) string {
+	var b strings.Builder
+	b.WriteString(" ON CONFLICT")
+	if len(conflict) > 0 {
+		b.WriteString(" (")
+		for i, column := range conflict {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(quoteIdent(d, column))
+		}
+		b.WriteString(")")
+	}
+	b.WriteString(" DO UPDATE SET ")
+	for i, column := range update {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		quoted := quoteIdent(d, column)
+		b.WriteString(quoted + "=excluded." + quoted)
+	}
+	return b.String()
+}
+
+// quoteIdent 列名加引号,支持 table.column,包含表达式、函数或已加引号的列名原样返回
+func quoteIdent(d Dialect, column string) string {
+	column = strings.TrimSpace(column)
+	if column == "" || column == "*" {
+		return column
+	}
+	for _, r := range column {
+		if !(r == '_' || r == '.' || r == '*' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127) {
+			return column
+		}
+	}
+	parts := strings.Split(column, ".")
+	for i, part := range parts {
+		// * 只能作为完整的一段,如 t.*,price*qty 等表达式原样返回
+		if part == "*" {
+			continue
+		}
+		if part == "" || strings.Contains(part, "*") {
+			return column
+		}
+		parts[i] = d.Quote(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteTableIdent 表名加引号,支持 "users u"、"users AS u" 形式的别名
+func quoteTableIdent(d Dialect, name string) string {
+	fields := strings.Fields(name)
+	if len(fields) == 0 || strings.ContainsAny(name, "(`\"") {
+		return name
+	}
+	fields[0] = quoteIdent(d, fields[0])
+	return strings.Join(fields, " ")
+}
+
+// dialect 当前构造器使用的方言,未指定时使用 MysqlDialect
+func (p *DbPool) dialect() Dialect {
+	if p.sqlDialect != nil {
+		return p.sqlDialect
+	}
+	if p.cluster != nil && p.cluster.dialect != nil {
+		return p.cluster.dialect
+	}
+	return MysqlDialect
+}
+
+// Dialect 当前使用的SQL方言
+func (p *DbPool) Dialect() Dialect {
+	return p.dialect()
+}
+
+// WithDialect 指定生成SQL使用的方言,用于 Query 创建的构造器
+func (p *DbPool) WithDialect(d Dialect) *DbPool {
+	q := p.clone()
+	q.sqlDialect = d
+	return q
+}
+
+// OnConflict 指定唯一键冲突列,用于 SQLite、PostgreSQL 的 Upsert,MySQL 忽略
+func (p *DbPool) OnConflict(columns ...string) *DbPool {
+	q := p.clone()
+	q.conflictColumns = columns
+	return q
+}
+
+// writer 创建使用当前方言的SQL拼接器,ctx 用于全局条件
+func (p *DbPool) writer(ctx context.Context) *sqlWriter {
+	return &sqlWriter{dialect: p.dialect(), ctx: ctx}
+}
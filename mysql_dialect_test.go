@@ -0,0 +1,45 @@
+package gosf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPostgresDialectSql(t *testing.T) {
+	q := Query("users u").WithDialect(PostgresDialect).
+		Select("u.id").
+		Where(Eq("u.status", 1)).
+		Where("u.id IN ?", []int{2, 3}).
+		OrderBy("u.id").
+		Limit(5).
+		Page(2)
+	sql, args, err := q.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT u.id FROM "users" u WHERE "u"."status" = $1 AND (u.id IN ($2,$3)) ORDER BY u.id LIMIT 5 OFFSET 5`
+	if sql != want {
+		t.Errorf("got  %q\nwant %q", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{1, 2, 3}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	cases := map[string]string{
+		"name":          "`name`",
+		"u.name":        "`u`.`name`",
+		"u.*":           "`u`.*",
+		"*":             "*",
+		"price*qty":     "price*qty",
+		"u.price*u.qty": "u.price*u.qty",
+		"COUNT(*)":      "COUNT(*)",
+		"`name`":        "`name`",
+	}
+	for column, want := range cases {
+		if got := quoteIdent(MysqlDialect, column); got != want {
+			t.Errorf("quoteIdent(%q) = %q, want %q", column, got, want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package gosf
+
+/**
+查询钩子,对同一个 Mysql 实例下的全部数据库生效,包括事务内的查询
+m := NewMysql(config)
+m.AddHook(SlowQueryHook(app.Logger, 200*time.Millisecond))
+m.AddHook(QueryHookFunc(func(ctx context.Context, e *QueryEvent) {
+	metrics.Observe(e.Database, e.Tags, e.Duration, e.Err)
+}))
+标签用于区分调用方,可在构造器上设置,也可通过 context 传递
+db.Table("order").Tag("order-list").Where("uid=?", uid).All()
+ctx = WithQueryTags(ctx, "cron", "report")
+*/
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryEvent 一次SQL执行的信息
+type QueryEvent struct {
+	Database     string        // 命名数据库
+	Sql          string        // 执行的SQL
+	Args         []interface{} // 绑定的参数
+	Tags         []string      // 查询标签
+	Exec         bool          // 是否为写入SQL
+	Start        time.Time     // 开始时间
+	Duration     time.Duration // 耗时,查询只统计到返回结果集,不含读取数据的时间
+	RowsAffected int64         // 写入影响的行数,查询时为0
+	Err          error         // 执行错误
+}
+
+// QueryHook 查询钩子,BeforeQuery 在执行前调用,返回的 context 用于本次执行与 AfterQuery
+type QueryHook interface {
+	BeforeQuery(ctx context.Context, e *QueryEvent) context.Context
+	AfterQuery(ctx context.Context, e *QueryEvent)
+}
+
+// QueryHookFunc 只需要执行后回调的钩子
+type QueryHookFunc func(ctx context.Context, e *QueryEvent)
+
+// BeforeQuery 不做处理
+func (f QueryHookFunc) BeforeQuery(ctx context.Context, e *QueryEvent) context.Context {
+	return ctx
+}
+
+// AfterQuery 执行后回调
+func (f QueryHookFunc) AfterQuery(ctx context.Context, e *QueryEvent) {
+	f(ctx, e)
+}
+
+// SlowQueryHook 耗时超过 threshold 的SQL写入日志,附带标签与错误,logger 为nil时使用标准输出
+// 参数可能包含个人信息,只记录参数个数
+func SlowQueryHook(logger *Logger, threshold time.Duration) QueryHook {
+	return QueryHookFunc(func(ctx context.Context, e *QueryEvent) {
+		if e.Duration < threshold {
+			return
+		}
+		v := []interface{}{"slow query", e.Database, e.Duration.String(), e.Sql, fmt.Sprintf("args:%d", len(e.Args))}
+		if len(e.Tags) > 0 {
+			v = append(v, "tags:"+strings.Join(e.Tags, ","))
+		}
+		if e.Err != nil {
+			v = append(v, e.Err)
+		}
+		if logger == nil {
+			fmt.Println(v...)
+			return
+		}
+		logger.Info(v...)
+	})
+}
+
+// queryHooks Mysql 实例的钩子列表
+type queryHooks struct {
+	mu    sync.RWMutex
+	hooks []QueryHook
+}
+
+func (h *queryHooks) list() []QueryHook {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.hooks
+}
+
+// AddHook 添加查询钩子,按添加顺序调用
+func (p *Mysql) AddHook(hook QueryHook) {
+	p.hooks.mu.Lock()
+	defer p.hooks.mu.Unlock()
+	// 复制后追加,已取出的列表不受影响
+	hooks := make([]QueryHook, 0, len(p.hooks.hooks)+1)
+	p.hooks.hooks = append(append(hooks, p.hooks.hooks...), hook)
+}
+
+// queryTagsKey context 中的查询标签
+type queryTagsKey struct{}
+
+// WithQueryTags 在 context 中附加查询标签,使用该 context 执行的SQL都会带上这些标签
+func WithQueryTags(ctx context.Context, tags ...string) context.Context {
+	if len(tags) == 0 {
+		return ctx
+	}
+	old, _ := ctx.Value(queryTagsKey{}).([]string)
+	merged := make([]string, 0, len(old)+len(tags))
+	merged = append(append(merged, old...), tags...)
+	return context.WithValue(ctx, queryTagsKey{}, merged)
+}
+
+// Tag 为当前查询添加标签,Table 创建的新查询不继承标签
+func (p *DbPool) Tag(tags ...string) *DbPool {
+	q := p.clone()
+	q.tags = append(q.tags, tags...)
+	return q
+}
+
+// run 执行SQL并调用钩子,记录最后执行的SQL
+func (p *DbPool) run(ctx context.Context, exec bool, query string, args []interface{}, fn func(ctx context.Context) (int64, error)) {
+	p.setLastSql(query)
+	var hooks []QueryHook
+	if p.cluster != nil {
+		hooks = p.cluster.hooks.list()
+	}
+	if len(hooks) == 0 {
+		_, _ = fn(ctx)
+		return
+	}
+	e := &QueryEvent{
+		Sql:   query,
+		Args:  args,
+		Exec:  exec,
+		Start: time.Now(),
+	}
+	e.Database = p.cluster.name
+	ctxTags, _ := ctx.Value(queryTagsKey{}).([]string)
+	if len(ctxTags) > 0 || len(p.tags) > 0 {
+		e.Tags = append(append(make([]string, 0, len(ctxTags)+len(p.tags)), ctxTags...), p.tags...)
+	}
+	for _, hook := range hooks {
+		ctx = hook.BeforeQuery(ctx, e)
+	}
+	e.RowsAffected, e.Err = fn(ctx)
+	e.Duration = time.Since(e.Start)
+	for _, hook := range hooks {
+		hook.AfterQuery(ctx, e)
+	}
+}
@@ -0,0 +1,70 @@
+package gosf
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryHookTags(t *testing.T) {
+	m, db := openSqliteMysql(t)
+	var got *QueryEvent
+	m.AddHook(QueryHookFunc(func(ctx context.Context, e *QueryEvent) { got = e }))
+	if _, err := db.Table("user").Insert(map[string]interface{}{"name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithQueryTags(context.Background(), "cron")
+	q := db.Table("user").Tag("report").Where("name=?", "a")
+	if _, err := q.AllCtx(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT * FROM "user" WHERE name=? LIMIT 10 OFFSET 0`
+	if got == nil || got.Database != "test" || got.Exec || got.Sql != want || got.Err != nil ||
+		!reflect.DeepEqual(got.Tags, []string{"cron", "report"}) || !reflect.DeepEqual(got.Args, []interface{}{"a"}) {
+		t.Fatalf("unexpected event %+v", got)
+	}
+	if q.LastSql() != want {
+		t.Errorf("unexpected last sql %q", q.LastSql())
+	}
+	if _, err := db.Table("user").Where("name=?", "a").Update(map[string]interface{}{"hits": 3}); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Exec || got.RowsAffected != 1 || got.Tags != nil {
+		t.Errorf("unexpected exec event %+v", got)
+	}
+	if _, err := db.FetchAllCtx(ctx, "SELECT nope FROM user"); err == nil || got.Err == nil {
+		t.Errorf("error should reach the hook: %+v", got)
+	}
+}
+
+func TestSlowQueryHook(t *testing.T) {
+	m, db := openSqliteMysql(t)
+	var buf bytes.Buffer
+	logger := &Logger{InfoLogger: log.New(&buf, "", 0)}
+	m.AddHook(SlowQueryHook(logger, time.Hour))
+	ctx := WithQueryTags(context.Background(), "cron")
+	if _, err := db.Table("user").Where("name=?", "secret@example.com").AllCtx(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("fast query logged: %s", buf.String())
+	}
+
+	m.AddHook(SlowQueryHook(logger, time.Nanosecond))
+	if _, err := db.Table("user").Where("name=?", "secret@example.com").AllCtx(ctx); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "slow query test") || !strings.Contains(out, `SELECT * FROM "user" WHERE name=?`) ||
+		!strings.Contains(out, "args:1") || !strings.Contains(out, "tags:cron") {
+		t.Errorf("unexpected slow query log: %s", out)
+	}
+	// 参数不写入日志
+	if strings.Contains(out, "secret@example.com") {
+		t.Errorf("slow query log contains args: %s", out)
+	}
+}
@@ -0,0 +1,271 @@
+package gosf
+
+/**
+数据表选项,对同一个命名数据库的全部查询生效
+db := m.MustDB("base")
+db.SetTableOptions("article", TableOptions{Timestamps: true, SoftDelete: true})
+Insert 自动写入 created_at、updated_at,Update、Increment 自动更新 updated_at,已传入的值不覆盖
+查询、更新、统计自动追加 deleted_at IS NULL,Delete 改为更新 deleted_at
+db.Table("article").WithTrashed().All()           // 包含已删除数据
+db.Table("article").OnlyTrashed().Where("id=?", 1).Restore()
+db.Table("article").Where("id=?", 1).ForceDelete() // 物理删除
+全局条件,按表名决定是否追加,返回 nil 时不追加,列名使用 qualifier 限定,表带别名时为别名
+db.AddScope("tenant", func(ctx context.Context, table, qualifier string) Cond {
+	if table == "config" {
+		return nil
+	}
+	return Eq(qualifier+".tenant_id", TenantFrom(ctx))
+})
+db.Table("article").WithoutScopes("tenant").All() // 不指定名称时忽略全部全局条件
+软删除与全局条件只作用于 Table 指定的主表,Join 关联的表不会追加,需要在 Join 的条件中自行限定
+db.Table("article a").Join("comment c", "c.article_id = a.id AND c.tenant_id = ?", tenantID)
+*/
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TableOptions 数据表选项
+type TableOptions struct {
+	Timestamps bool             // 自动维护创建时间与更新时间
+	CreatedAt  string           // 创建时间列,默认 created_at
+	UpdatedAt  string           // 更新时间列,默认 updated_at
+	SoftDelete bool             // 软删除,Delete 时更新删除时间列
+	DeletedAt  string           // 删除时间列,默认 deleted_at
+	Now        func() time.Time // 当前时间,默认 time.Now
+}
+
+// Scope 全局查询条件,table 为不含别名的表名,qualifier 为引用列时使用的别名或表名,返回 nil 时不追加条件
+// 只作用于主表,Join 关联的表不追加
+type Scope func(ctx context.Context, table, qualifier string) Cond
+
+// 软删除查询方式
+const (
+	trashedExclude = iota // 排除已删除数据
+	trashedWith           // 包含已删除数据
+	trashedOnly           // 只查询已删除数据
+)
+
+// namedScope 命名的全局条件
+type namedScope struct {
+	name  string
+	scope Scope
+}
+
+// tableRegistry 命名数据库的数据表选项与全局条件
+type tableRegistry struct {
+	mu     sync.RWMutex
+	tables map[string]TableOptions
+	scopes []namedScope
+}
+
+func (r *tableRegistry) options(table string) (TableOptions, bool) {
+	if r == nil {
+		return TableOptions{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	opts, ok := r.tables[table]
+	return opts, ok
+}
+
+func (r *tableRegistry) scopeList() []namedScope {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.scopes
+}
+
+// SetTableOptions 设置数据表选项,对同一个命名数据库的全部查询生效,Query 创建的构造器调用无效
+func (p *DbPool) SetTableOptions(table string, opts TableOptions) {
+	if opts.CreatedAt == "" {
+		opts.CreatedAt = "created_at"
+	}
+	if opts.UpdatedAt == "" {
+		opts.UpdatedAt = "updated_at"
+	}
+	if opts.DeletedAt == "" {
+		opts.DeletedAt = "deleted_at"
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	r := p.registry()
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tables == nil {
+		r.tables = make(map[string]TableOptions)
+	}
+	r.tables[table] = opts
+}
+
+// AddScope 添加全局查询条件,同名时替换,对同一个命名数据库的查询、更新、删除、统计生效
+func (p *DbPool) AddScope(name string, scope Scope) {
+	r := p.registry()
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// 复制后修改,已取出的列表不受影响
+	scopes := make([]namedScope, 0, len(r.scopes)+1)
+	for _, item := range r.scopes {
+		if item.name != name {
+			scopes = append(scopes, item)
+		}
+	}
+	r.scopes = append(scopes, namedScope{name: name, scope: scope})
+}
+
+// registry 数据表选项注册表,未绑定数据库的构造器返回nil
+func (p *DbPool) registry() *tableRegistry {
+	if p.cluster == nil {
+		return nil
+	}
+	return &p.cluster.tables
+}
+
+// WithTrashed 查询包含已软删除的数据
+func (p *DbPool) WithTrashed() *DbPool {
+	q := p.clone()
+	q.trashed = trashedWith
+	return q
+}
+
+// OnlyTrashed 只查询已软删除的数据
+func (p *DbPool) OnlyTrashed() *DbPool {
+	q := p.clone()
+	q.trashed = trashedOnly
+	return q
+}
+
+// WithoutScopes 忽略指定名称的全局条件,不指定名称时忽略全部
+func (p *DbPool) WithoutScopes(names ...string) *DbPool {
+	q := p.clone()
+	if len(names) == 0 {
+		q.withoutScopes = []string{"*"}
+		return q
+	}
+	q.withoutScopes = append(q.withoutScopes[:len(q.withoutScopes):len(q.withoutScopes)], names...)
+	return q
+}
+
+// ForceDelete 物理删除,不受软删除影响,返回影响的行数
+func (p *DbPool) ForceDelete() (affectRows int, err error) {
+	w := p.writer(context.Background())
+	// 组合删除数据SQL
+	w.WriteString(fmt.Sprintf("DELETE FROM %v", w.quoteTable(p.tableName)))
+	p.handlerWhere(w)
+	if w.err != nil {
+		return 0, w.err
+	}
+	return p.execAffected(context.Background(), w.String(), w.args...)
+}
+
+// Restore 恢复软删除的数据,返回影响的行数
+func (p *DbPool) Restore() (affectRows int, err error) {
+	opts, ok := p.tableOptions()
+	if !ok || !opts.SoftDelete {
+		return 0, fmt.Errorf("gosf: table %q has no soft delete", p.tableName)
+	}
+	return p.OnlyTrashed().Update(map[string]interface{}{opts.DeletedAt: nil})
+}
+
+// baseTable 去除别名后的表名
+func (p *DbPool) baseTable() string {
+	fields := strings.Fields(p.tableName)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.Trim(fields[0], "`\"")
+}
+
+// tableQualifier 引用列时使用的表名或别名
+func (p *DbPool) tableQualifier() string {
+	fields := strings.Fields(p.tableName)
+	if len(fields) > 1 {
+		return fields[len(fields)-1]
+	}
+	return p.baseTable()
+}
+
+// tableOptions 当前表的选项
+func (p *DbPool) tableOptions() (TableOptions, bool) {
+	if p.fromSub != nil || p.tableName == "" {
+		return TableOptions{}, false
+	}
+	return p.registry().options(p.baseTable())
+}
+
+// scopeConds 软删除与全局条件
+func (p *DbPool) scopeConds(ctx context.Context) []Cond {
+	if p.fromSub != nil || p.tableName == "" {
+		return nil
+	}
+	var conds []Cond
+	if opts, ok := p.tableOptions(); ok && opts.SoftDelete {
+		column := p.tableQualifier() + "." + opts.DeletedAt
+		switch p.trashed {
+		case trashedExclude:
+			conds = append(conds, IsNull(column))
+		case trashedOnly:
+			conds = append(conds, IsNotNull(column))
+		}
+	}
+	scopes := p.registry().scopeList()
+	if len(scopes) == 0 {
+		return conds
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	table, qualifier := p.baseTable(), p.tableQualifier()
+	for _, item := range scopes {
+		if p.scopeDisabled(item.name) {
+			continue
+		}
+		if cond := item.scope(ctx, table, qualifier); cond != nil {
+			conds = append(conds, cond)
+		}
+	}
+	return conds
+}
+
+func (p *DbPool) scopeDisabled(name string) bool {
+	for _, item := range p.withoutScopes {
+		if item == "*" || item == name {
+			return true
+		}
+	}
+	return false
+}
+
+// withTimestamps 按表选项补充时间列,created 为 true 时同时写入创建时间,不修改传入的map
+func (p *DbPool) withTimestamps(params map[string]interface{}, created bool) map[string]interface{} {
+	opts, ok := p.tableOptions()
+	if !ok || !opts.Timestamps {
+		return params
+	}
+	now := opts.Now()
+	ret := make(map[string]interface{}, len(params)+2)
+	for k, v := range params {
+		ret[k] = v
+	}
+	if _, ok := ret[opts.UpdatedAt]; !ok {
+		ret[opts.UpdatedAt] = now
+	}
+	if created {
+		if _, ok := ret[opts.CreatedAt]; !ok {
+			ret[opts.CreatedAt] = now
+		}
+	}
+	return ret
+}
@@ -0,0 +1,65 @@
+package gosf
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSqliteSoftDeleteAndScopes(t *testing.T) {
+	db := openSqlite(t)
+	if _, err := db.Execute("CREATE TABLE post (id INTEGER PRIMARY KEY, tenant_id INTEGER, title TEXT, created_at DATETIME, updated_at DATETIME, deleted_at DATETIME)"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	db.SetTableOptions("post", TableOptions{Timestamps: true, SoftDelete: true, Now: func() time.Time { return now }})
+	type tenantKey struct{}
+	db.AddScope("tenant", func(ctx context.Context, table, qualifier string) Cond {
+		if id, ok := ctx.Value(tenantKey{}).(int); ok {
+			return Eq(qualifier+".tenant_id", id)
+		}
+		return nil
+	})
+	for i, tenant := range []int{1, 1, 2} {
+		if _, err := db.Table("post").Insert(map[string]interface{}{"id": i + 1, "tenant_id": tenant, "title": "t"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var created []time.Time
+	if err := db.Table("post").Select("created_at").Limit(0).AllInto(&created); err != nil || len(created) != 3 || !created[0].Equal(now) {
+		t.Fatalf("timestamps not set: %v %v", created, err)
+	}
+	if n, err := db.Table("post").Where("id=?", 1).Delete(); err != nil || n != 1 {
+		t.Fatalf("soft delete: %v %v", n, err)
+	}
+	ctx := context.WithValue(context.Background(), tenantKey{}, 1)
+	count := func(q *DbPool) int {
+		n, err := q.CountCtx(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	if n := count(db.Table("post")); n != 1 {
+		t.Errorf("expected 1 live post for tenant 1, got %d", n)
+	}
+	// 带别名时条件使用别名
+	if n := count(db.Table("post p").Where("p.id>?", 0)); n != 1 {
+		t.Errorf("expected 1 live post for tenant 1 with alias, got %d", n)
+	}
+	if n := count(db.Table("post").WithTrashed()); n != 2 {
+		t.Errorf("expected 2 posts with trashed, got %d", n)
+	}
+	if n := count(db.Table("post").WithoutScopes()); n != 2 {
+		t.Errorf("expected 2 live posts without scopes, got %d", n)
+	}
+	if n, err := db.Table("post").OnlyTrashed().Restore(); err != nil || n != 1 {
+		t.Fatalf("restore: %v %v", n, err)
+	}
+	if n, err := db.Table("post").Where("id=?", 3).ForceDelete(); err != nil || n != 1 {
+		t.Fatalf("force delete: %v %v", n, err)
+	}
+	if n := count(db.Table("post").WithTrashed().WithoutScopes("tenant")); n != 2 {
+		t.Errorf("expected 2 posts after force delete, got %d", n)
+	}
+}
@@ -0,0 +1,218 @@
+package gosf
+
+/**
+分页
+page, err := db.Table("article").Where("status=?", 1).OrderBy("id DESC").Paginate(ctx, 2, 20)
+page.Items / page.Total / page.Pages / page.HasNext
+var list []Article
+page, err := db.Table("article").PaginateInto(ctx, 2, 20, &list)
+游标分页,适用于无限滚动,token 为上一次返回的 Next 或 Prev,首次传空字符串
+page, err := db.Table("article").Where("status=?", 1).CursorPaginate(ctx, "id DESC", 20, token)
+page.Items / page.Next / page.Prev
+*/
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor 游标分页的 token 无法解析
+var ErrInvalidCursor = errors.New("gosf: invalid pagination cursor")
+
+// Pagination 分页结果
+type Pagination struct {
+	Items   []map[string]interface{} // 当前页数据,PaginateInto 时为nil
+	Total   int                      // 总记录数
+	Page    int                      // 当前页码,从1开始
+	Size    int                      // 每页条数
+	Pages   int                      // 总页数
+	HasNext bool                     // 是否有下一页
+}
+
+// CursorPage 游标分页结果
+type CursorPage struct {
+	Items   []map[string]interface{}
+	Next    string // 下一页游标,没有下一页时为空
+	Prev    string // 上一页游标,没有上一页时为空
+	HasNext bool
+	HasPrev bool
+}
+
+// cursorToken 游标内容
+type cursorToken struct {
+	Value     interface{} `json:"v"`
+	Backwards bool        `json:"b,omitempty"`
+}
+
+// Paginate 分页查询,一次返回当前页数据、总数、总页数与是否有下一页
+func (p *DbPool) Paginate(ctx context.Context, page, size int) (*Pagination, error) {
+	result, q, err := p.pagination(ctx, page, size)
+	if err != nil || result.Total == 0 {
+		return result, err
+	}
+	if result.Items, err = q.AllCtx(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PaginateInto 分页查询,当前页数据扫描到 dest（slice指针）
+func (p *DbPool) PaginateInto(ctx context.Context, page, size int, dest interface{}) (*Pagination, error) {
+	result, q, err := p.pagination(ctx, page, size)
+	if err != nil || result.Total == 0 {
+		return result, err
+	}
+	if err = q.AllIntoCtx(ctx, dest); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// pagination 统计总数并返回当前页的查询构造器
+func (p *DbPool) pagination(ctx context.Context, page, size int) (*Pagination, *DbPool, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		return nil, nil, fmt.Errorf("gosf: invalid page size %d", size)
+	}
+	total, err := p.CountCtx(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	pages := (total + size - 1) / size
+	result := &Pagination{
+		Total:   total,
+		Page:    page,
+		Size:    size,
+		Pages:   pages,
+		HasNext: page < pages,
+	}
+	q := *p
+	q.page = page
+	q.limit = size
+	q.limitSet = true
+	return result, &q, nil
+}
+
+// CursorPaginate 游标分页,column 为唯一且有序的列,可带 DESC 如 "id DESC",token 为空时从第一页开始
+func (p *DbPool) CursorPaginate(ctx context.Context, column string, size int, token string) (*CursorPage, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("gosf: invalid page size %d", size)
+	}
+	fields := strings.Fields(column)
+	if len(fields) == 0 {
+		return nil, errors.New("gosf: cursor column is empty")
+	}
+	name := fields[0]
+	desc := len(fields) > 1 && strings.EqualFold(fields[1], "DESC")
+	key := name
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	key = strings.Trim(key, "`")
+
+	var cursor *cursorToken
+	if token != "" {
+		var err error
+		if cursor, err = decodeCursor(token); err != nil {
+			return nil, err
+		}
+	}
+	backwards := cursor != nil && cursor.Backwards
+	// 向前翻页时反向排序,取到数据后再倒序
+	ascending := desc == backwards
+	q := *p
+	if ascending {
+		q.orderCondition = []string{quoteIdent(p.dialect(), name) + " ASC"}
+	} else {
+		q.orderCondition = []string{quoteIdent(p.dialect(), name) + " DESC"}
+	}
+	if cursor != nil {
+		var cond Cond
+		if ascending {
+			cond = Gt(name, cursor.Value)
+		} else {
+			cond = Lt(name, cursor.Value)
+		}
+		if q.whereCondition == nil {
+			q.whereCondition = cond
+		} else {
+			q.whereCondition = And(q.whereCondition, cond)
+		}
+	}
+	// 多取一条判断是否还有数据
+	q.page = 1
+	q.limit = size + 1
+	q.limitSet = true
+	items, err := q.AllCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	more := len(items) > size
+	if more {
+		items = items[:size]
+	}
+	if backwards {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	result := &CursorPage{Items: items}
+	if backwards {
+		result.HasPrev = more
+		result.HasNext = true
+	} else {
+		result.HasNext = more
+		result.HasPrev = cursor != nil
+	}
+	if len(items) == 0 {
+		return result, nil
+	}
+	if _, ok := items[0][key]; !ok {
+		return nil, fmt.Errorf("gosf: cursor column %q not in result", name)
+	}
+	if result.HasNext {
+		result.Next = encodeCursor(cursorToken{Value: items[len(items)-1][key]})
+	}
+	if result.HasPrev {
+		result.Prev = encodeCursor(cursorToken{Value: items[0][key], Backwards: true})
+	}
+	return result, nil
+}
+
+func encodeCursor(token cursorToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (*cursorToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var cursor cursorToken
+	if err = decoder.Decode(&cursor); err != nil || cursor.Value == nil {
+		return nil, ErrInvalidCursor
+	}
+	// 数字还原为整数或浮点数,避免精度丢失
+	if number, ok := cursor.Value.(json.Number); ok {
+		if n, err := number.Int64(); err == nil {
+			cursor.Value = n
+		} else if u, err := strconv.ParseUint(number.String(), 10, 64); err == nil {
+			cursor.Value = u
+		} else if f, err := number.Float64(); err == nil {
+			cursor.Value = f
+		} else {
+			cursor.Value = number.String()
+		}
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,54 @@
+package gosf
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	db := openSqlite(t)
+	seedUsers(t, db, 3)
+	if _, err := db.Execute("UPDATE user SET hits=0 WHERE id=2"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	page, err := db.Table("user").Where(Gt("hits", 0)).OrderBy("id").Paginate(ctx, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || !page.HasNext || len(page.Items) != 1 || page.Items[0]["name"] != "u1" {
+		t.Errorf("unexpected page %+v", page)
+	}
+	page, err = db.Table("user").Where(Gt("hits", 0)).OrderBy("id").Paginate(ctx, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 2 || page.HasNext || len(page.Items) != 1 || page.Items[0]["name"] != "u3" {
+		t.Errorf("unexpected page %+v", page)
+	}
+}
+
+func TestCursorToken(t *testing.T) {
+	// 超过 float64 精度的整数需要原样还原
+	cases := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{int64(1)<<62 + 1, int64(1)<<62 + 1},
+		{uint64(1)<<63 + 1, uint64(1)<<63 + 1},
+		{1.5, 1.5},
+		{"2024-01-02", "2024-01-02"},
+	}
+	for _, c := range cases {
+		cursor, err := decodeCursor(encodeCursor(cursorToken{Value: c.value, Backwards: true}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cursor.Value != c.want || !cursor.Backwards {
+			t.Errorf("unexpected cursor %+v, want %v", cursor, c.want)
+		}
+	}
+	if _, err := decodeCursor("not-a-token"); err != ErrInvalidCursor {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package gosf
+
+/**
+关联查询、分组过滤与子查询
+DB("base").Table("orders o").Distinct().Select("u.id", "u.name", "SUM(o.amount) AS total").
+	LeftJoin("users u", "u.id = o.user_id").GroupBy("u.id").Having("SUM(o.amount) > ?", 100).All()
+子查询
+paid := Query("orders").Select("user_id").Where("status=?", 1)
+DB("base").Table("users").Where(In("id", paid)).All()
+stat := Query("orders").Select("user_id", "COUNT(*) AS n").GroupBy("user_id")
+DB("base").From(stat, "s").JoinSub(stat, "t", "t.user_id = s.user_id").All()
+*/
+import (
+	"context"
+	"sync/atomic"
+)
+
+// joinClause 关联条件
+type joinClause struct {
+	kind  string  // JOIN 类型
+	table string  // 关联表,可带别名
+	sub   *DbPool // 关联子查询
+	alias string  // 子查询别名
+	on    Cond
+}
+
+func (j joinClause) writeTo(w *sqlWriter) {
+	w.WriteString(" " + j.kind + " ")
+	if j.sub != nil {
+		w.writeArg(j.sub)
+		w.WriteString(" AS " + w.quoteColumn(j.alias))
+	} else {
+		w.WriteString(w.quoteTable(j.table))
+	}
+	if j.on != nil {
+		w.WriteString(" ON ")
+		j.on.writeTo(w)
+	}
+}
+
+// Query 创建不绑定连接池的查询构造器,用于子查询或只生成SQL,执行查询时返回 ErrNoDatabase
+func Query(table string) *DbPool {
+	return (&DbPool{lastSql: &atomic.Value{}, page: 1, limit: 10}).Table(table)
+}
+
+// ToSql 生成查询SQL与占位符参数
+func (p *DbPool) ToSql() (string, []interface{}, error) {
+	return p.sql(context.Background())
+}
+
+// Distinct 查询去重
+func (p *DbPool) Distinct() *DbPool {
+	q := p.clone()
+	q.distinct = true
+	return q
+}
+
+// From 使用子查询作为数据来源
+func (p *DbPool) From(sub *DbPool, alias string) *DbPool {
+	q := p.clone()
+	q.fromSub = sub
+	q.fromAlias = alias
+	return q
+}
+
+// Join 内关联,table 可带别名如 "users u",on 为带 ? 占位符的关联条件
+func (p *DbPool) Join(table string, on string, args ...interface{}) *DbPool {
+	return p.join("INNER JOIN", table, nil, "", on, args)
+}
+
+// LeftJoin 左关联
+func (p *DbPool) LeftJoin(table string, on string, args ...interface{}) *DbPool {
+	return p.join("LEFT JOIN", table, nil, "", on, args)
+}
+
+// RightJoin 右关联
+func (p *DbPool) RightJoin(table string, on string, args ...interface{}) *DbPool {
+	return p.join("RIGHT JOIN", table, nil, "", on, args)
+}
+
+// JoinSub 内关联子查询
+func (p *DbPool) JoinSub(sub *DbPool, alias string, on string, args ...interface{}) *DbPool {
+	return p.join("INNER JOIN", "", sub, alias, on, args)
+}
+
+// LeftJoinSub 左关联子查询
+func (p *DbPool) LeftJoinSub(sub *DbPool, alias string, on string, args ...interface{}) *DbPool {
+	return p.join("LEFT JOIN", "", sub, alias, on, args)
+}
+
+func (p *DbPool) join(kind, table string, sub *DbPool, alias string, on string, args []interface{}) *DbPool {
+	join := joinClause{kind: kind, table: table, sub: sub, alias: alias}
+	if on != "" {
+		join.on = Raw(on, args...)
+	}
+	q := p.clone()
+	q.joins = append(q.joins, join)
+	return q
+}
+
+// Having 分组过滤条件,入参同 Where,多次调用以AND连接
+func (p *DbPool) Having(query interface{}, values ...interface{}) *DbPool {
+	cond := toCond(query, values)
+	q := p.clone()
+	if q.havingCondition == nil {
+		q.havingCondition = cond
+	} else {
+		q.havingCondition = And(q.havingCondition, cond)
+	}
+	return q
+}
+
+// existsCond EXISTS 子查询条件
+type existsCond struct {
+	sub *DbPool
+	not bool
+}
+
+func (c existsCond) writeTo(w *sqlWriter) {
+	if c.not {
+		w.WriteString("NOT ")
+	}
+	w.WriteString("EXISTS ")
+	w.writeArg(c.sub)
+}
+
+// Exists 子查询存在数据
+func Exists(sub *DbPool) Cond {
+	return existsCond{sub: sub}
+}
+
+// NotExists 子查询不存在数据
+func NotExists(sub *DbPool) Cond {
+	return existsCond{sub: sub, not: true}
+}
@@ -0,0 +1,93 @@
+package gosf
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDbPoolJoinHaving(t *testing.T) {
+	paid := Query("orders").Select("user_id").Where("status=?", 1)
+	q := Query("users u").Distinct().
+		Select("u.id", "COUNT(o.id) AS n").
+		LeftJoin("orders o", "o.user_id = u.id AND o.type = ?", 2).
+		Where(In("u.id", paid)).
+		GroupBy("u.id").
+		Having("COUNT(o.id) > ?", 3).
+		OrderBy("n DESC").
+		Limit(5)
+	sql, args, err := q.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT DISTINCT u.id,COUNT(o.id) AS n FROM `users` u LEFT JOIN `orders` o ON o.user_id = u.id AND o.type = ?" +
+		" WHERE `u`.`id` IN (SELECT user_id FROM `orders` WHERE status=?) GROUP BY u.id HAVING COUNT(o.id) > ? ORDER BY n DESC LIMIT 0, 5"
+	if sql != want {
+		t.Errorf("got  %q\nwant %q", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{2, 1, 3}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestDbPoolFromSub(t *testing.T) {
+	stat := Query("orders").Select("user_id", "COUNT(*) AS n").Where(Gt("amount", 10)).GroupBy("user_id")
+	q := Query("").From(stat, "s").Select("s.n").Where(Exists(Query("users").Select("1").Where("users.id = s.user_id"))).Limit(0)
+	sql, args, err := q.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT s.n FROM (SELECT user_id,COUNT(*) AS n FROM `orders` WHERE `amount` > ? GROUP BY user_id) AS `s`" +
+		" WHERE EXISTS (SELECT 1 FROM `users` WHERE users.id = s.user_id)"
+	if sql != want {
+		t.Errorf("got  %q\nwant %q", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{10}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestDbPoolImmutable(t *testing.T) {
+	base := Query("orders").Where("status=?", 1).Join("users u", "u.id = orders.user_id")
+	a := base.Where("amount>?", 10).LeftJoin("shops s", "s.id = orders.shop_id")
+	b := base.Where("amount<?", 5).Join("coupons c", "c.id = orders.coupon_id").OrderBy("id DESC")
+	baseSql, baseArgs, _ := base.ToSql()
+	if baseSql != "SELECT * FROM `orders` INNER JOIN `users` u ON u.id = orders.user_id WHERE status=? LIMIT 0, 10" ||
+		!reflect.DeepEqual(baseArgs, []interface{}{1}) {
+		t.Errorf("base query changed: %q %v", baseSql, baseArgs)
+	}
+	aSql, _, _ := a.ToSql()
+	bSql, bArgs, _ := b.ToSql()
+	if strings.Contains(aSql, "coupons") || strings.Contains(bSql, "shops") || !reflect.DeepEqual(bArgs, []interface{}{1, 5}) {
+		t.Errorf("derived queries leaked: %q / %q %v", aSql, bSql, bArgs)
+	}
+	if clone := base.Clone(); clone == base {
+		t.Error("Clone returned the same builder")
+	}
+}
+
+func TestQueryWithoutDatabase(t *testing.T) {
+	ctx := context.Background()
+	q := Query("user").Where("id=?", 1)
+	if _, err := q.AllCtx(ctx); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("AllCtx: expected ErrNoDatabase, got %v", err)
+	}
+	if _, err := q.GetCtx(ctx); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("GetCtx: expected ErrNoDatabase, got %v", err)
+	}
+	if _, err := q.CountCtx(ctx); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("CountCtx: expected ErrNoDatabase, got %v", err)
+	}
+	if _, err := q.Update(map[string]interface{}{"name": "a"}); !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("Update: expected ErrNoDatabase, got %v", err)
+	}
+	err := q.Transaction(ctx, func(tx *DbPool) error {
+		t.Error("fn should not run without database")
+		return nil
+	})
+	if !errors.Is(err, ErrNoDatabase) {
+		t.Errorf("Transaction: expected ErrNoDatabase, got %v", err)
+	}
+}
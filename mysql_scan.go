@@ -0,0 +1,520 @@
+package gosf
+
+/**
+结构体扫描
+type User struct {
+	Id        uint64          `db:"id"`
+	Name      string          `db:"name"`
+	Email     *string         `db:"email"`      // NULL 时为nil
+	Profile   map[string]any  `db:"profile"`    // JSON 列自动解析
+	Extra     Extra           `db:"extra,json"` // 显式声明JSON列
+	CreatedAt time.Time       `db:"created_at"`
+	Ignored   string          `db:"-"`
+}
+var user User
+err := DB("base").Table("user").Where("id=?", 1).GetInto(&user)
+var users []User
+err := DB("base").Table("user").AllInto(&users)
+*/
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// 字段映射信息
+type fieldInfo struct {
+	index  []int // 字段索引路径,支持匿名嵌套结构体
+	asJSON bool  // 是否按JSON解析
+}
+
+// 结构体字段映射缓存 reflect.Type => map[列名]*fieldInfo
+var structFieldCache sync.Map
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	bytesType   = reflect.TypeOf([]byte(nil))
+)
+
+// 时间列解析格式
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02",
+	"15:04:05",
+}
+
+// GetInto 获取第一条数据并扫描到 dest（结构体指针或基础类型指针）,未查到数据时返回 ErrNoRows
+func (p *DbPool) GetInto(dest interface{}) error {
+	return p.GetIntoCtx(context.Background(), dest)
+}
+
+// GetIntoCtx 获取第一条数据并扫描到 dest,未查到数据时返回 ErrNoRows
+func (p *DbPool) GetIntoCtx(ctx context.Context, dest interface{}) error {
+	q := p.Limit(1)
+	GetSql, args, err := q.sql(ctx)
+	if err != nil {
+		return err
+	}
+	return q.FetchOneIntoCtx(ctx, dest, GetSql, args...)
+}
+
+// AllInto 获取多条数据并扫描到 dest（slice指针,元素可以是结构体、结构体指针或基础类型）
+func (p *DbPool) AllInto(dest interface{}) error {
+	return p.AllIntoCtx(context.Background(), dest)
+}
+
+// AllIntoCtx 获取多条数据并扫描到 dest
+func (p *DbPool) AllIntoCtx(ctx context.Context, dest interface{}) error {
+	GetSql, args, err := p.sql(ctx)
+	if err != nil {
+		return err
+	}
+	return p.FetchAllIntoCtx(ctx, dest, GetSql, args...)
+}
+
+// FetchOneIntoCtx 执行SQL并将第一条数据扫描到 dest,未查到数据时返回 ErrNoRows
+func (p *DbPool) FetchOneIntoCtx(ctx context.Context, dest interface{}, Sql string, args ...interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("gosf: scan destination must be a non-nil pointer")
+	}
+	rows, err := p.queryRows(ctx, Sql, args...)
+	if err != nil {
+		return err
+	}
+	defer closeRows(rows)
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("rows columns error: %w", err)
+	}
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("rows iterate error: %w", err)
+		}
+		return ErrNoRows
+	}
+	fields, err := columnFields(v.Elem().Type(), columns)
+	if err != nil {
+		return err
+	}
+	if err = scanRow(rows, v.Elem(), columns, fields, p.location()); err != nil {
+		return err
+	}
+	return rows.Close()
+}
+
+// FetchAllIntoCtx 执行SQL并将多条数据扫描到 dest（slice指针）
+func (p *DbPool) FetchAllIntoCtx(ctx context.Context, dest interface{}, Sql string, args ...interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.New("gosf: scan destination must be a pointer to slice")
+	}
+	rows, err := p.queryRows(ctx, Sql, args...)
+	if err != nil {
+		return err
+	}
+	return scanAll(rows, v.Elem(), p.location())
+}
+
+// scanAll 扫描全部数据行到slice,loc 为解析时间文本使用的时区
+func scanAll(rows *sql.Rows, slice reflect.Value, loc *time.Location) error {
+	defer closeRows(rows)
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("rows columns error: %w", err)
+	}
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	baseType := elemType
+	if isPtr {
+		baseType = elemType.Elem()
+	}
+	fields, err := columnFields(baseType, columns)
+	if err != nil {
+		return err
+	}
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	for rows.Next() {
+		elem := reflect.New(baseType).Elem()
+		if err = scanRow(rows, elem, columns, fields, loc); err != nil {
+			return err
+		}
+		if isPtr {
+			elem = elem.Addr()
+		}
+		result = reflect.Append(result, elem)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("rows iterate error: %w", err)
+	}
+	slice.Set(result)
+	return nil
+}
+
+// scanRow 扫描当前行到 dest, fields 为 nil 时表示 dest 为基础类型,取第一列
+func scanRow(rows *sql.Rows, dest reflect.Value, columns []string, fields []*fieldInfo, loc *time.Location) error {
+	values := make([]interface{}, len(fields))
+	ptrs := make([]interface{}, len(fields))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return fmt.Errorf("rows scan error: %w", err)
+	}
+	if !isStructDest(dest.Type()) {
+		if err := assignValue(dest, values[0], false, loc); err != nil {
+			return fmt.Errorf("scan column %q: %w", columns[0], err)
+		}
+		return nil
+	}
+	for i, field := range fields {
+		if field == nil {
+			continue
+		}
+		if err := assignValue(fieldByIndex(dest, field.index), values[i], field.asJSON, loc); err != nil {
+			return fmt.Errorf("scan column %q: %w", columns[i], err)
+		}
+	}
+	return nil
+}
+
+// columnFields 按列顺序返回对应字段,未映射的列为nil
+func columnFields(t reflect.Type, columns []string) ([]*fieldInfo, error) {
+	fields := make([]*fieldInfo, len(columns))
+	if !isStructDest(t) {
+		if len(columns) == 0 {
+			return nil, errors.New("gosf: query returned no columns")
+		}
+		return fields, nil
+	}
+	mapping := structFields(t)
+	for i, column := range columns {
+		if field, ok := mapping[column]; ok {
+			fields[i] = field
+		} else if field, ok = mapping[strings.ToLower(column)]; ok {
+			fields[i] = field
+		}
+	}
+	return fields, nil
+}
+
+// isStructDest 判断是否按结构体字段映射
+func isStructDest(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || t == timeType {
+		return false
+	}
+	return !reflect.PtrTo(t).Implements(scannerType)
+}
+
+// structFields 获取结构体列名到字段的映射,结果按类型缓存
+func structFields(t reflect.Type) map[string]*fieldInfo {
+	if cached, ok := structFieldCache.Load(t); ok {
+		return cached.(map[string]*fieldInfo)
+	}
+	mapping := make(map[string]*fieldInfo)
+	collectFields(t, nil, mapping)
+	cached, _ := structFieldCache.LoadOrStore(t, mapping)
+	return cached.(map[string]*fieldInfo)
+}
+
+// collectFields 递归收集字段,外层字段优先
+func collectFields(t reflect.Type, parent []int, mapping map[string]*fieldInfo) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct && isStructDest(fieldType) {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = snakeCase(field.Name)
+		}
+		if _, exists := mapping[name]; exists {
+			continue
+		}
+		index := append(append([]int{}, parent...), i)
+		mapping[name] = &fieldInfo{index: index, asJSON: opts == "json" || isJSONKind(fieldType)}
+	}
+	for _, field := range embedded {
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		collectFields(fieldType, append(append([]int{}, parent...), field.Index...), mapping)
+	}
+}
+
+// isJSONKind map、非[]byte的slice、普通结构体按JSON解析
+func isJSONKind(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Struct:
+		return isStructDest(t)
+	}
+	return false
+}
+
+// fieldByIndex 按索引路径获取字段,嵌套指针为nil时自动创建
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// snakeCase 字段名转下划线列名 UserID => user_id
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// assignValue 将驱动返回的值赋给 dst,处理NULL、时间、无符号整数、[]byte与JSON
+func assignValue(dst reflect.Value, src interface{}, asJSON bool, loc *time.Location) error {
+	if dst.Kind() == reflect.Ptr {
+		if src == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		elem := reflect.New(dst.Type().Elem())
+		if err := assignValue(elem.Elem(), src, asJSON, loc); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	}
+	if dst.CanAddr() && dst.Addr().Type().Implements(scannerType) {
+		return dst.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if asJSON {
+		var data []byte
+		switch s := src.(type) {
+		case []byte:
+			data = s
+		case string:
+			data = []byte(s)
+		default:
+			return fmt.Errorf("cannot decode %T as json into %s", src, dst.Type())
+		}
+		return json.Unmarshal(data, dst.Addr().Interface())
+	}
+	if dst.Type() == timeType {
+		t, err := toTime(src, loc)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if dst.Type() == bytesType {
+		switch s := src.(type) {
+		case []byte:
+			dst.SetBytes(append([]byte(nil), s...))
+		case string:
+			dst.SetBytes([]byte(s))
+		default:
+			dst.SetBytes([]byte(fmt.Sprint(s)))
+		}
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Interface:
+		if b, ok := src.([]byte); ok {
+			src = string(b)
+		}
+		dst.Set(reflect.ValueOf(src))
+		return nil
+	case reflect.String:
+		switch s := src.(type) {
+		case []byte:
+			dst.SetString(string(s))
+		case time.Time:
+			dst.SetString(s.Format("2006-01-02 15:04:05"))
+		default:
+			dst.SetString(fmt.Sprint(s))
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt64(src)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowInt(n) {
+			return fmt.Errorf("value %d overflows %s", n, dst.Type())
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toUint64(src)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowUint(n) {
+			return fmt.Errorf("value %d overflows %s", n, dst.Type())
+		}
+		dst.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := toFloat64(src)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		switch s := src.(type) {
+		case bool:
+			dst.SetBool(s)
+			return nil
+		case []byte:
+			b, err := strconv.ParseBool(string(s))
+			if err != nil {
+				return err
+			}
+			dst.SetBool(b)
+			return nil
+		}
+		n, err := toInt64(src)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(n != 0)
+		return nil
+	}
+	sv := reflect.ValueOf(src)
+	if sv.Type().ConvertibleTo(dst.Type()) {
+		dst.Set(sv.Convert(dst.Type()))
+		return nil
+	}
+	return fmt.Errorf("unsupported scan, storing %T into %s", src, dst.Type())
+}
+
+func toInt64(src interface{}) (int64, error) {
+	switch s := src.(type) {
+	case int64:
+		return s, nil
+	case uint64:
+		if s > 1<<63-1 {
+			return 0, fmt.Errorf("value %d overflows int64", s)
+		}
+		return int64(s), nil
+	case float64:
+		return int64(s), nil
+	case bool:
+		if s {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return strconv.ParseInt(string(s), 10, 64)
+	case string:
+		return strconv.ParseInt(s, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to int", src)
+}
+
+func toUint64(src interface{}) (uint64, error) {
+	switch s := src.(type) {
+	case uint64:
+		return s, nil
+	case int64:
+		if s < 0 {
+			return 0, fmt.Errorf("value %d overflows uint64", s)
+		}
+		return uint64(s), nil
+	case float64:
+		return uint64(s), nil
+	case []byte:
+		return strconv.ParseUint(string(s), 10, 64)
+	case string:
+		return strconv.ParseUint(s, 10, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to uint", src)
+}
+
+func toFloat64(src interface{}) (float64, error) {
+	switch s := src.(type) {
+	case float64:
+		return s, nil
+	case float32:
+		return float64(s), nil
+	case int64:
+		return float64(s), nil
+	case uint64:
+		return float64(s), nil
+	case []byte:
+		return strconv.ParseFloat(string(s), 64)
+	case string:
+		return strconv.ParseFloat(s, 64)
+	}
+	return 0, fmt.Errorf("cannot convert %T to float", src)
+}
+
+// toTime 解析时间列,未开启parseTime时驱动返回的是字符串,按 loc 时区解析
+func toTime(src interface{}, loc *time.Location) (time.Time, error) {
+	var value string
+	switch s := src.(type) {
+	case time.Time:
+		return s, nil
+	case []byte:
+		value = string(s)
+	case string:
+		value = s
+	default:
+		return time.Time{}, fmt.Errorf("cannot convert %T to time.Time", src)
+	}
+	if value == "" || strings.HasPrefix(value, "0000-00-00") {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as time.Time", value)
+}
@@ -0,0 +1,139 @@
+package gosf
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type scanBase struct {
+	Id        uint64    `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type scanUser struct {
+	scanBase
+	Name    string            `db:"name"`
+	Email   *string           `db:"email"`
+	Nick    sql.NullString    `db:"nick"`
+	Profile map[string]string `db:"profile"`
+	Raw     []byte            `db:"raw"`
+	UserID  int
+	Skip    string `db:"-"`
+}
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"Name":      "name",
+		"UserID":    "user_id",
+		"CreatedAt": "created_at",
+		"HTTPCode":  "http_code",
+	}
+	for in, want := range cases {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStructFields(t *testing.T) {
+	fields := structFields(reflect.TypeOf(scanUser{}))
+	for _, name := range []string{"id", "created_at", "name", "email", "nick", "profile", "raw", "user_id"} {
+		if _, ok := fields[name]; !ok {
+			t.Errorf("column %q not mapped", name)
+		}
+	}
+	if _, ok := fields["skip"]; ok {
+		t.Error("db:\"-\" field should not be mapped")
+	}
+	if !fields["profile"].asJSON {
+		t.Error("map field should be decoded as json")
+	}
+	if fields["raw"].asJSON {
+		t.Error("[]byte field should not be decoded as json")
+	}
+}
+
+func TestAssignValue(t *testing.T) {
+	var user scanUser
+	v := reflect.ValueOf(&user).Elem()
+	loc := time.FixedZone("CST", 8*3600)
+	fields := structFields(v.Type())
+	values := map[string]interface{}{
+		"id":         []byte("18446744073709551615"),
+		"created_at": []byte("2024-05-06 07:08:09"),
+		"name":       []byte("gosf"),
+		"email":      nil,
+		"nick":       []byte("oy"),
+		"profile":    []byte(`{"city":"sz"}`),
+		"raw":        []byte{1, 2},
+		"user_id":    int64(7),
+	}
+	for column, value := range values {
+		field := fields[column]
+		if err := assignValue(fieldByIndex(v, field.index), value, field.asJSON, loc); err != nil {
+			t.Fatalf("assign %s: %v", column, err)
+		}
+	}
+	if user.Id != 18446744073709551615 {
+		t.Errorf("unexpected id %d", user.Id)
+	}
+	// 时间文本按连接的时区解析
+	if !user.CreatedAt.Equal(time.Date(2024, 5, 6, 7, 8, 9, 0, loc)) {
+		t.Errorf("unexpected created_at %v", user.CreatedAt)
+	}
+	if user.Name != "gosf" || user.Email != nil || !user.Nick.Valid || user.Nick.String != "oy" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if user.Profile["city"] != "sz" || len(user.Raw) != 2 || user.UserID != 7 {
+		t.Errorf("unexpected user %+v", user)
+	}
+
+	var small int8
+	if err := assignValue(reflect.ValueOf(&small).Elem(), int64(300), false, loc); err == nil {
+		t.Error("expected overflow error")
+	}
+}
+
+func TestScanLocation(t *testing.T) {
+	if loc := dsnLocation("user:pwd@tcp(127.0.0.1:3306)/base"); loc != time.UTC {
+		t.Errorf("default dsn loc = %v", loc)
+	}
+	if loc := dsnLocation("user:pwd@tcp(127.0.0.1:3306)/base?loc=Local"); loc != time.Local {
+		t.Errorf("dsn loc=Local = %v", loc)
+	}
+	// 注册的连接池无法得知时区,使用 time.Local
+	db := openSqlite(t)
+	if db.location() != time.Local {
+		t.Errorf("registered pool location = %v", db.location())
+	}
+	var at time.Time
+	if err := db.FetchOneIntoCtx(context.Background(), &at, "SELECT '2024-05-06 07:08:09'"); err != nil {
+		t.Fatal(err)
+	}
+	if !at.Equal(time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)) {
+		t.Errorf("unexpected time %v", at)
+	}
+}
+
+func TestScanErrorColumnName(t *testing.T) {
+	db := openSqlite(t)
+	// SQLite 允许 INTEGER 列存入文本
+	if _, err := db.Execute("INSERT INTO user (name, hits) VALUES ('a', 'many')"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := db.Table("user").AllCtx(ctx); err == nil || !strings.Contains(err.Error(), `"hits"`) {
+		t.Errorf("expected parse error for column hits, got %v", err)
+	}
+	var users []struct {
+		Name string `db:"name"`
+		Hits int    `db:"hits"`
+	}
+	if err := db.Table("user").AllIntoCtx(ctx, &users); err == nil || !strings.Contains(err.Error(), `"hits"`) {
+		t.Errorf("expected scan error for column hits, got %v", err)
+	}
+}
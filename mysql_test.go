@@ -0,0 +1,154 @@
+package gosf
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openMemory(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func openSqlite(t *testing.T) *DbPool {
+	t.Helper()
+	_, db := openSqliteMysql(t)
+	return db
+}
+
+// openSqliteMysql 内存 SQLite 数据库,同时返回所属的 Mysql 实例,用于添加钩子
+func openSqliteMysql(t *testing.T) (*Mysql, *DbPool) {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 内存数据库每个连接独立,只保留一个连接
+	sqlDB.SetMaxOpenConns(1)
+	m := &Mysql{}
+	if err = m.RegisterWith("test", SqliteDialect, sqlDB); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+	db := m.MustDB("test")
+	if _, err = db.Execute("CREATE TABLE user (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL UNIQUE, hits INTEGER NOT NULL DEFAULT 0)"); err != nil {
+		t.Fatal(err)
+	}
+	return m, db
+}
+
+func TestMysqlRegistry(t *testing.T) {
+	m := &Mysql{}
+	defer m.Close()
+	if _, err := m.DB("base"); !errors.Is(err, ErrUnknownDB) {
+		t.Fatalf("expected ErrUnknownDB, got %v", err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("MustDB should panic for unknown database")
+			}
+		}()
+		m.MustDB("base")
+	}()
+
+	first := openMemory(t)
+	if err := m.RegisterWith("base", SqliteDialect, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Register("base", openMemory(t)); err == nil {
+		t.Error("duplicate Register should fail")
+	}
+	if err := m.Register("nil", nil); err == nil {
+		t.Error("Register nil db should fail")
+	}
+	if err := m.Register("report", openMemory(t)); err != nil {
+		t.Fatal(err)
+	}
+	if names := m.Names(); !reflect.DeepEqual(names, []string{"base", "report"}) {
+		t.Errorf("unexpected names %v", names)
+	}
+	if db := m.MustDB("base"); db.GetPool() != first || db.dialect() != SqliteDialect {
+		t.Error("MustDB returned wrong pool")
+	}
+
+	// Replace 关闭旧连接池并沿用方言
+	second := openMemory(t)
+	if err := m.Replace("base", second); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Ping(); err == nil {
+		t.Error("replaced pool should be closed")
+	}
+	if db := m.MustDB("base"); db.GetPool() != second || db.dialect() != SqliteDialect {
+		t.Error("Replace should keep the dialect and use the new pool")
+	}
+
+	if err := m.Remove("report"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("report"); !errors.Is(err, ErrUnknownDB) {
+		t.Errorf("expected ErrUnknownDB, got %v", err)
+	}
+	if m.Has("report") {
+		t.Error("report should be removed")
+	}
+}
+
+func TestQueryContext(t *testing.T) {
+	db := openSqlite(t)
+	if _, err := db.Table("user").Insert(map[string]interface{}{"name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.Table("user").GetCtx(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetCtx with canceled context: %v", err)
+	}
+	if _, err := db.Table("user").AllCtx(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("AllCtx with canceled context: %v", err)
+	}
+
+	// 执行中超时中断查询
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := db.FetchOneCtx(ctx, "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c WHERE x < 1000000000) SELECT COUNT(*) AS n FROM c")
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Fatalf("expected deadline exceeded, err=%v after %v", err, time.Since(start))
+	}
+	assertConnFree(t, db)
+}
+
+func TestErrNoRows(t *testing.T) {
+	db := openSqlite(t)
+	ctx := context.Background()
+	if _, err := db.Table("user").Where("id=?", 1).GetCtx(ctx); !errors.Is(err, ErrNoRows) {
+		t.Errorf("GetCtx: expected ErrNoRows, got %v", err)
+	}
+	if _, err := db.FetchOneCtx(ctx, "SELECT * FROM user WHERE id=?", 1); !errors.Is(err, ErrNoRows) {
+		t.Errorf("FetchOneCtx: expected ErrNoRows, got %v", err)
+	}
+	if rows, err := db.Table("user").AllCtx(ctx); err != nil || len(rows) != 0 {
+		t.Errorf("AllCtx on empty table: %v %v", rows, err)
+	}
+	// SQL错误不是 ErrNoRows
+	_, err := db.Table("missing").GetCtx(ctx)
+	if err == nil || errors.Is(err, ErrNoRows) {
+		t.Errorf("GetCtx on missing table: %v", err)
+	}
+	if _, err = db.FetchOneCtx(ctx, "SELECT nope FROM user"); err == nil || errors.Is(err, ErrNoRows) {
+		t.Errorf("FetchOneCtx with bad column: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package gosf
+
+/**
+事务
+err := DB("base").Transaction(ctx, func(tx *DbPool) error {
+	id, err := tx.Table("orders").Insert(order)
+	if err != nil {
+		return err // 回滚
+	}
+	// 嵌套调用使用保存点,内层失败只回滚到保存点
+	_ = tx.Transaction(ctx, func(tx *DbPool) error {
+		_, err := tx.Table("order_log").Insert(log)
+		return err
+	})
+	return nil // 提交
+})
+*/
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// sqlConn 执行SQL的连接, *sql.DB 与 *sql.Tx 均实现
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conn 获取当前执行连接,存在事务时读写均在事务中执行
+func (p *DbPool) conn() sqlConn {
+	if p.tx != nil {
+		return p.tx
+	}
+	return p.pool
+}
+
+// session 基于当前连接创建新的构造器,不继承查询条件,保留方言与标签,共享最后执行的SQL
+func (p *DbPool) session() *DbPool {
+	return &DbPool{
+		pool:       p.pool,
+		cluster:    p.cluster,
+		usePrimary: p.usePrimary,
+		tx:         p.tx,
+		txDepth:    p.txDepth,
+		sqlDialect: p.sqlDialect,
+		tags:       p.tags[:len(p.tags):len(p.tags)],
+		lastSql:    p.lastSql,
+		page:       1,
+		limit:      10,
+	}
+}
+
+// InTx 是否处于事务中
+func (p *DbPool) InTx() bool {
+	return p.tx != nil
+}
+
+// Transaction 在事务中执行 fn,fn 返回错误或panic时回滚,否则提交
+// 在事务内再次调用时使用保存点实现嵌套,内层回滚不影响外层事务
+func (p *DbPool) Transaction(ctx context.Context, fn func(tx *DbPool) error) error {
+	return p.TransactionOpts(ctx, nil, fn)
+}
+
+// TransactionOpts 指定隔离级别等选项执行事务,嵌套调用时 opts 无效
+func (p *DbPool) TransactionOpts(ctx context.Context, opts *sql.TxOptions, fn func(tx *DbPool) error) (err error) {
+	child := p.session()
+	if p.tx != nil {
+		// 嵌套事务使用保存点
+		child.txDepth++
+		savepoint := fmt.Sprintf("gosf_sp_%d", child.txDepth)
+		if _, err = child.exec(ctx, "SAVEPOINT "+savepoint); err != nil {
+			return err
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				_, _ = child.exec(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+				panic(r)
+			}
+			if err != nil {
+				if _, rbErr := child.exec(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+					err = fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+				}
+				return
+			}
+			_, err = child.exec(ctx, "RELEASE SAVEPOINT "+savepoint)
+		}()
+		return fn(child)
+	}
+
+	if p.pool == nil {
+		return ErrNoDatabase
+	}
+	tx, err := p.pool.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	child.tx = tx
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil && rbErr != sql.ErrTxDone {
+				err = fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+	return fn(child)
+}
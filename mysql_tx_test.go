@@ -0,0 +1,98 @@
+package gosf
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNestedTransactionSavepoint(t *testing.T) {
+	db := openSqlite(t)
+	ctx := context.Background()
+	errInner := errors.New("inner failed")
+
+	err := db.Transaction(ctx, func(tx *DbPool) error {
+		if _, err := tx.Table("user").Insert(map[string]interface{}{"name": "outer"}); err != nil {
+			return err
+		}
+		innerErr := tx.Transaction(ctx, func(inner *DbPool) error {
+			if !inner.InTx() {
+				t.Error("inner should run in the transaction")
+			}
+			if _, err := inner.Table("user").Insert(map[string]interface{}{"name": "inner"}); err != nil {
+				return err
+			}
+			return errInner
+		})
+		if !errors.Is(innerErr, errInner) {
+			t.Errorf("expected inner error, got %v", innerErr)
+		}
+		// 内层回滚后外层事务仍可继续
+		_, err := tx.Table("user").Insert(map[string]interface{}{"name": "after"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	if err = db.Table("user").OrderBy("id ASC").Pluck(ctx, "name", &names); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "outer" || names[1] != "after" {
+		t.Errorf("unexpected rows %v", names)
+	}
+}
+
+func TestTransactionPanicRollback(t *testing.T) {
+	db := openSqlite(t)
+	ctx := context.Background()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("expected re-panic with boom, got %v", r)
+			}
+		}()
+		_ = db.Transaction(ctx, func(tx *DbPool) error {
+			if _, err := tx.Table("user").Insert(map[string]interface{}{"name": "a"}); err != nil {
+				return err
+			}
+			return tx.Transaction(ctx, func(inner *DbPool) error {
+				if _, err := inner.Table("user").Insert(map[string]interface{}{"name": "b"}); err != nil {
+					return err
+				}
+				panic("boom")
+			})
+		})
+		t.Error("panic should propagate out of Transaction")
+	}()
+
+	// 回滚后连接已释放,数据未写入
+	n, err := db.Table("user").CountCtx(ctx)
+	if err != nil || n != 0 {
+		t.Fatalf("count=%d err=%v", n, err)
+	}
+}
+
+func TestTransactionKeepsTagsAndDialect(t *testing.T) {
+	m, db := openSqliteMysql(t)
+	var events []*QueryEvent
+	m.AddHook(QueryHookFunc(func(ctx context.Context, e *QueryEvent) { events = append(events, e) }))
+
+	// SQLite 支持 $1 占位符与双引号,可以验证 PostgreSQL 方言
+	err := db.Tag("checkout").WithDialect(PostgresDialect).Transaction(context.Background(), func(tx *DbPool) error {
+		_, err := tx.Table("user").Insert(map[string]interface{}{"name": "a"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	e := events[0]
+	if e.Sql != `INSERT INTO "user" ("name") VALUES ($1)` || !reflect.DeepEqual(e.Tags, []string{"checkout"}) {
+		t.Errorf("unexpected event sql=%q tags=%v", e.Sql, e.Tags)
+	}
+}
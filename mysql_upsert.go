@@ -0,0 +1,182 @@
+package gosf
+
+/**
+写入扩展,全部使用占位符绑定参数
+存在则更新指定列,不指定时更新全部列
+id, err := db.Table("user_stat").Upsert(map[string]interface{}{"user_id": 1, "name": "a", "hits": 1}, "name")
+计数增减,可同时更新其他列
+n, err := db.Table("article").Where("id=?", 1).Increment("hits", 1, map[string]interface{}{"updated_at": now})
+n, err := db.Table("goods").Where("id=? AND stock>=?", 1, 2).Decrement("stock", 2)
+忽略重复与替换
+id, err := db.Table("tag").InsertIgnore(map[string]interface{}{"name": "go"})
+id, err := db.Table("tag").Replace(map[string]interface{}{"id": 1, "name": "go"})
+批量存在则更新
+n, err := db.Table("user_stat").BatchUpsert(rows, "hits")
+批量写入的分批、缺失列补齐与自增ID见 BatchInsertCtx
+*/
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Upsert 插入数据,唯一键冲突时更新 updateColumns 指定的列,不指定时更新全部列,返回最后的ID
+func (p *DbPool) Upsert(params map[string]interface{}, updateColumns ...string) (lastId int, err error) {
+	params = p.withTimestamps(params, true)
+	if len(updateColumns) == 0 {
+		updateColumns = p.withoutCreatedAt(sortedKeys(params))
+	} else {
+		updateColumns = p.withUpdatedAt(updateColumns)
+	}
+	return p.insert(InsertOrUpdate, params, updateColumns)
+}
+
+// InsertIgnore 插入数据,唯一键冲突时忽略,返回最后的ID,被忽略时为0
+func (p *DbPool) InsertIgnore(params map[string]interface{}) (lastId int, err error) {
+	return p.insert(InsertOrIgnore, params, nil)
+}
+
+// Replace 插入数据,唯一键冲突时删除旧数据后插入,返回最后的ID
+func (p *DbPool) Replace(params map[string]interface{}) (lastId int, err error) {
+	return p.insert(InsertOrReplace, params, nil)
+}
+
+// BatchUpsert 批量插入,唯一键冲突时更新 updateColumns 指定的列,不指定时更新全部列,返回影响的行数
+func (p *DbPool) BatchUpsert(params []map[string]interface{}, updateColumns ...string) (affectRows int, err error) {
+	result, err := p.batchInsert(context.Background(), InsertOrUpdate, params, updateColumns, BatchOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return result.Affected, nil
+}
+
+// Increment 按where条件将 column 增加 amount,extra 为同时更新的其他列,返回影响的行数
+func (p *DbPool) Increment(column string, amount interface{}, extra ...map[string]interface{}) (affectRows int, err error) {
+	return p.incr(column, "+", amount, extra)
+}
+
+// Decrement 按where条件将 column 减少 amount,extra 为同时更新的其他列,返回影响的行数
+func (p *DbPool) Decrement(column string, amount interface{}, extra ...map[string]interface{}) (affectRows int, err error) {
+	return p.incr(column, "-", amount, extra)
+}
+
+func (p *DbPool) incr(column, op string, amount interface{}, extra []map[string]interface{}) (int, error) {
+	w := p.writer(context.Background())
+	quoted := w.quoteColumn(column)
+	w.WriteString(fmt.Sprintf("UPDATE %v SET %s=%s%s", w.quoteTable(p.tableName), quoted, quoted, op))
+	w.writeArg(amount)
+	if opts, ok := p.tableOptions(); ok && opts.Timestamps {
+		extra = append([]map[string]interface{}{{opts.UpdatedAt: opts.Now()}}, extra...)
+	}
+	for _, params := range extra {
+		for _, k := range sortedKeys(params) {
+			w.WriteString("," + w.quoteColumn(k) + "=")
+			w.writeArg(params[k])
+		}
+	}
+	p.handlerWhere(w)
+	if w.err != nil {
+		return 0, w.err
+	}
+	return p.execAffected(context.Background(), w.String(), w.args...)
+}
+
+// insert 插入单条数据,mode 为插入方式,InsertOrUpdate 时唯一键冲突更新 updateColumns
+// 方言不支持返回自增ID时返回0
+func (p *DbPool) insert(mode InsertMode, params map[string]interface{}, updateColumns []string) (int, error) {
+	if len(params) == 0 {
+		return 0, errors.New("gosf: insert without columns")
+	}
+	params = p.withTimestamps(params, true)
+	columns := sortedKeys(params)
+	w := p.writer(context.Background())
+	if err := p.writeInsert(w, mode, columns); err != nil {
+		return 0, err
+	}
+	w.WriteString(" VALUES ")
+	writeInsertRow(w, columns, params)
+	if err := p.writeConflict(w, mode, updateColumns); err != nil {
+		return 0, err
+	}
+	// 执行，存在事务时在事务中执行
+	retData, err := p.exec(context.Background(), w.String(), w.args...)
+	if err != nil {
+		return 0, err
+	}
+	if !w.sqlDialect().LastInsertId() {
+		return 0, nil
+	}
+	LastId, err := retData.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(LastId), nil
+}
+
+// writeInsert 拼接 INSERT INTO `table` (`a`,`b`)
+func (p *DbPool) writeInsert(w *sqlWriter, mode InsertMode, columns []string) error {
+	verb, err := w.sqlDialect().InsertVerb(mode)
+	if err != nil {
+		return err
+	}
+	w.WriteString(verb + " INTO " + w.quoteTable(p.tableName) + " (")
+	for i, column := range columns {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString(w.quoteColumn(column))
+	}
+	w.WriteString(")")
+	return nil
+}
+
+// writeInsertRow 拼接一行 (?,?)
+func writeInsertRow(w *sqlWriter, columns []string, row map[string]interface{}) {
+	w.WriteString("(")
+	for i, column := range columns {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		w.writeArg(row[column])
+	}
+	w.WriteString(")")
+}
+
+// writeConflict 拼接唯一键冲突处理,如 ON DUPLICATE KEY UPDATE `a`=VALUES(`a`)
+func (p *DbPool) writeConflict(w *sqlWriter, mode InsertMode, updateColumns []string) error {
+	clause, err := w.sqlDialect().OnConflict(mode, p.conflictColumns, updateColumns)
+	if err != nil {
+		return err
+	}
+	w.WriteString(clause)
+	return nil
+}
+
+// withUpdatedAt 表开启时间戳时,冲突更新的列追加更新时间列
+func (p *DbPool) withUpdatedAt(columns []string) []string {
+	opts, ok := p.tableOptions()
+	if !ok || !opts.Timestamps {
+		return columns
+	}
+	for _, column := range columns {
+		if column == opts.UpdatedAt {
+			return columns
+		}
+	}
+	return append(columns[:len(columns):len(columns)], opts.UpdatedAt)
+}
+
+// withoutCreatedAt 冲突更新时不修改创建时间列
+func (p *DbPool) withoutCreatedAt(columns []string) []string {
+	opts, ok := p.tableOptions()
+	if !ok || !opts.Timestamps {
+		return columns
+	}
+	ret := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if column != opts.CreatedAt {
+			ret = append(ret, column)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,128 @@
+package gosf_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/oyjz/gosf"
+	"github.com/oyjz/gosf/dbtest"
+)
+
+func TestUpsertSql(t *testing.T) {
+	row := map[string]interface{}{"name": "a", "hits": 1}
+	rows := []map[string]interface{}{row, {"name": "b", "hits": 2}}
+	cases := []struct {
+		dialect gosf.Dialect
+		upsert  string
+		ignore  string
+		replace string
+		batch   string
+		incr    string
+	}{
+		{
+			gosf.MysqlDialect,
+			"INSERT INTO `user` (`hits`,`name`) VALUES (?,?) ON DUPLICATE KEY UPDATE `hits`=VALUES(`hits`)",
+			"INSERT IGNORE INTO `user` (`hits`,`name`) VALUES (?,?)",
+			"REPLACE INTO `user` (`hits`,`name`) VALUES (?,?)",
+			"INSERT INTO `user` (`hits`,`name`) VALUES (?,?),(?,?) ON DUPLICATE KEY UPDATE `hits`=VALUES(`hits`),`name`=VALUES(`name`)",
+			"UPDATE `user` SET `hits`=`hits`+? WHERE name=?",
+		},
+		{
+			gosf.SqliteDialect,
+			`INSERT INTO "user" ("hits","name") VALUES (?,?) ON CONFLICT ("name") DO UPDATE SET "hits"=excluded."hits"`,
+			`INSERT OR IGNORE INTO "user" ("hits","name") VALUES (?,?)`,
+			`INSERT OR REPLACE INTO "user" ("hits","name") VALUES (?,?)`,
+			`INSERT INTO "user" ("hits","name") VALUES (?,?),(?,?) ON CONFLICT ("name") DO UPDATE SET "hits"=excluded."hits","name"=excluded."name"`,
+			`UPDATE "user" SET "hits"="hits"+? WHERE name=?`,
+		},
+		{
+			gosf.PostgresDialect,
+			`INSERT INTO "user" ("hits","name") VALUES ($1,$2) ON CONFLICT ("name") DO UPDATE SET "hits"=excluded."hits"`,
+			`INSERT INTO "user" ("hits","name") VALUES ($1,$2) ON CONFLICT DO NOTHING`,
+			"", // PostgreSQL 不支持 REPLACE
+			`INSERT INTO "user" ("hits","name") VALUES ($1,$2),($3,$4) ON CONFLICT ("name") DO UPDATE SET "hits"=excluded."hits","name"=excluded."name"`,
+			`UPDATE "user" SET "hits"="hits"+$1 WHERE name=$2`,
+		},
+	}
+	for _, c := range cases {
+		mock := dbtest.New()
+		m := &gosf.Mysql{}
+		if err := mock.RegisterWith(m, "base", c.dialect); err != nil {
+			t.Fatal(err)
+		}
+		db := m.MustDB("base").Table("user").OnConflict("name")
+		name := c.dialect.Name()
+
+		mock.ExpectExec(c.upsert).WithArgs(1, "a").WillReturnResult(1, 1)
+		if _, err := db.Upsert(row, "hits"); err != nil {
+			t.Errorf("%s upsert: %v", name, err)
+		}
+		mock.ExpectExec(c.ignore).WithArgs(1, "a").WillReturnResult(0, 0)
+		if _, err := db.InsertIgnore(row); err != nil {
+			t.Errorf("%s insert ignore: %v", name, err)
+		}
+		if c.replace != "" {
+			mock.ExpectExec(c.replace).WithArgs(1, "a").WillReturnResult(1, 2)
+			if _, err := db.Replace(row); err != nil {
+				t.Errorf("%s replace: %v", name, err)
+			}
+		} else if _, err := db.Replace(row); err == nil {
+			t.Errorf("%s replace should fail", name)
+		}
+		if c.dialect == gosf.MysqlDialect {
+			mock.ExpectQuery("SELECT @@max_allowed_packet").WillReturnRows(dbtest.NewRows("@@max_allowed_packet").AddRow(4 << 20))
+		}
+		mock.ExpectExec(c.batch).WithArgs(1, "a", 2, "b").WillReturnResult(2, 2)
+		if _, err := db.BatchUpsert(rows); err != nil {
+			t.Errorf("%s batch upsert: %v", name, err)
+		}
+		mock.ExpectExec(c.incr).WithArgs(2, "a").WillReturnResult(0, 1)
+		if _, err := db.Where("name=?", "a").Increment("hits", 2); err != nil {
+			t.Errorf("%s increment: %v", name, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		m.Close()
+	}
+}
+
+func TestUpsertSqlite(t *testing.T) {
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	m := &gosf.Mysql{}
+	defer m.Close()
+	if err = m.RegisterWith("test", gosf.SqliteDialect, sqlDB); err != nil {
+		t.Fatal(err)
+	}
+	db := m.MustDB("test")
+	if _, err = db.Execute("CREATE TABLE user (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL UNIQUE, hits INTEGER NOT NULL DEFAULT 0)"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err = db.Table("user").Insert(map[string]interface{}{"name": name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if id, err := db.Table("user").OnConflict("name").Upsert(map[string]interface{}{"name": "a", "hits": 5}, "hits"); err != nil || id == 0 {
+		t.Fatalf("upsert: %v %v", id, err)
+	}
+	if _, err = db.Table("user").InsertIgnore(map[string]interface{}{"name": "b", "hits": 9}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := db.Table("user").Where("name=?", "b").Increment("hits", 2); err != nil || n != 1 {
+		t.Fatalf("increment: %v %v", n, err)
+	}
+	var hits []int
+	if err = db.Table("user").OrderBy("id").Pluck(context.Background(), "hits", &hits); err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0] != 5 || hits[1] != 2 {
+		t.Errorf("unexpected hits %v", hits)
+	}
+}
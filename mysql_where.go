@@ -0,0 +1,467 @@
+package gosf
+
+/**
+WHERE条件构造,所有值均以占位符绑定,不会改写调用方传入的SQL片段
+DB("base").Table("goods").Where("brand=?", "apple").Where(Or(Eq("status", 1), In("type", []int{1, 2}))).All()
+DB("base").Table("goods").Where(And(Between("price", 10, 100), Like("name", "%phone%"), IsNull("deleted_at"))).All()
+DB("base").Table("goods").Where(map[string]interface{}{"brand": "apple", "status": 1}).OrWhere("id IN ?", ids).All()
+*/
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Cond WHERE条件,通过 Eq、In、Between、Or 等函数构造
+type Cond interface {
+	writeTo(w *sqlWriter)
+}
+
+// sqlWriter SQL拼接器,收集占位符参数与拼接过程中的错误
+type sqlWriter struct {
+	strings.Builder
+	dialect Dialect
+	ctx     context.Context // 执行查询的 context,用于全局条件
+	args    []interface{}
+	err     error
+}
+
+// writeArg 写入一个占位符并记录参数,子查询以 (SELECT ...) 形式写入
+func (w *sqlWriter) writeArg(v interface{}) {
+	if sub, ok := v.(*DbPool); ok {
+		w.WriteString("(")
+		sub.writeSelect(w, true)
+		w.WriteString(")")
+		return
+	}
+	w.args = append(w.args, v)
+	w.WriteString(w.sqlDialect().Placeholder(len(w.args)))
+}
+
+// setErr 记录第一个错误
+func (w *sqlWriter) setErr(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// quoteColumn 按当前方言给列名加引号
+func (w *sqlWriter) quoteColumn(column string) string {
+	return quoteIdent(w.sqlDialect(), column)
+}
+
+// quoteTable 按当前方言给表名加引号
+func (w *sqlWriter) quoteTable(name string) string {
+	return quoteTableIdent(w.sqlDialect(), name)
+}
+
+// sqlDialect 拼接使用的方言,未指定时使用 MysqlDialect
+func (w *sqlWriter) sqlDialect() Dialect {
+	if w.dialect == nil {
+		return MysqlDialect
+	}
+	return w.dialect
+}
+
+// compareCond 比较条件 col op ?
+type compareCond struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (c compareCond) writeTo(w *sqlWriter) {
+	w.WriteString(w.quoteColumn(c.column))
+	w.WriteString(" ")
+	w.WriteString(c.op)
+	w.WriteString(" ")
+	w.writeArg(c.value)
+}
+
+// Eq 等于,值为nil时等同于 IsNull
+func Eq(column string, value interface{}) Cond {
+	if value == nil {
+		return IsNull(column)
+	}
+	return compareCond{column, "=", value}
+}
+
+// Neq 不等于,值为nil时等同于 IsNotNull
+func Neq(column string, value interface{}) Cond {
+	if value == nil {
+		return IsNotNull(column)
+	}
+	return compareCond{column, "<>", value}
+}
+
+// Gt 大于
+func Gt(column string, value interface{}) Cond {
+	return compareCond{column, ">", value}
+}
+
+// Gte 大于等于
+func Gte(column string, value interface{}) Cond {
+	return compareCond{column, ">=", value}
+}
+
+// Lt 小于
+func Lt(column string, value interface{}) Cond {
+	return compareCond{column, "<", value}
+}
+
+// Lte 小于等于
+func Lte(column string, value interface{}) Cond {
+	return compareCond{column, "<=", value}
+}
+
+// Like 模糊匹配,通配符需由调用方传入
+func Like(column string, pattern string) Cond {
+	return compareCond{column, "LIKE", pattern}
+}
+
+// NotLike 模糊不匹配
+func NotLike(column string, pattern string) Cond {
+	return compareCond{column, "NOT LIKE", pattern}
+}
+
+// inCond IN / NOT IN 条件
+type inCond struct {
+	column string
+	not    bool
+	values []interface{}
+}
+
+func (c inCond) writeTo(w *sqlWriter) {
+	// 空集合:IN 恒为假,NOT IN 恒为真
+	if len(c.values) == 0 {
+		if c.not {
+			w.WriteString("1=1")
+		} else {
+			w.WriteString("1=0")
+		}
+		return
+	}
+	w.WriteString(w.quoteColumn(c.column))
+	if c.not {
+		w.WriteString(" NOT IN ")
+	} else {
+		w.WriteString(" IN ")
+	}
+	// 子查询 IN (SELECT ...)
+	if sub, ok := c.values[0].(*DbPool); ok && len(c.values) == 1 {
+		w.writeArg(sub)
+		return
+	}
+	w.WriteString("(")
+	for i, v := range c.values {
+		if i > 0 {
+			w.WriteString(",")
+		}
+		w.writeArg(v)
+	}
+	w.WriteString(")")
+}
+
+// In 包含,values 可以是多个值、单个slice或子查询
+func In(column string, values ...interface{}) Cond {
+	return inCond{column: column, values: flattenValues(values)}
+}
+
+// NotIn 不包含,values 可以是多个值、单个slice或子查询
+func NotIn(column string, values ...interface{}) Cond {
+	return inCond{column: column, not: true, values: flattenValues(values)}
+}
+
+// betweenCond BETWEEN 条件
+type betweenCond struct {
+	column     string
+	not        bool
+	start, end interface{}
+}
+
+func (c betweenCond) writeTo(w *sqlWriter) {
+	w.WriteString(w.quoteColumn(c.column))
+	if c.not {
+		w.WriteString(" NOT BETWEEN ")
+	} else {
+		w.WriteString(" BETWEEN ")
+	}
+	w.writeArg(c.start)
+	w.WriteString(" AND ")
+	w.writeArg(c.end)
+}
+
+// Between 区间,包含两端
+func Between(column string, start, end interface{}) Cond {
+	return betweenCond{column: column, start: start, end: end}
+}
+
+// NotBetween 不在区间内
+func NotBetween(column string, start, end interface{}) Cond {
+	return betweenCond{column: column, not: true, start: start, end: end}
+}
+
+// nullCond IS NULL / IS NOT NULL 条件
+type nullCond struct {
+	column string
+	not    bool
+}
+
+func (c nullCond) writeTo(w *sqlWriter) {
+	w.WriteString(w.quoteColumn(c.column))
+	if c.not {
+		w.WriteString(" IS NOT NULL")
+	} else {
+		w.WriteString(" IS NULL")
+	}
+}
+
+// IsNull 为空
+func IsNull(column string) Cond {
+	return nullCond{column: column}
+}
+
+// IsNotNull 不为空
+func IsNotNull(column string) Cond {
+	return nullCond{column: column, not: true}
+}
+
+// rawCond 原始SQL片段,仅替换 ? 占位符,slice参数展开为 (?,?,?)
+type rawCond struct {
+	query string
+	args  []interface{}
+}
+
+func (c rawCond) writeTo(w *sqlWriter) {
+	argIndex := 0
+	var quote rune
+	runes := []rune(c.query)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		// 引号内的内容原样输出
+		if quote != 0 {
+			w.WriteRune(r)
+			if r == '\\' && i+1 < len(runes) {
+				i++
+				w.WriteRune(runes[i])
+			} else if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '\'', '"', '`':
+			quote = r
+			w.WriteRune(r)
+		case '?':
+			if argIndex >= len(c.args) {
+				w.setErr(fmt.Errorf("gosf: not enough args for %q", c.query))
+				w.WriteRune(r)
+				continue
+			}
+			arg := c.args[argIndex]
+			argIndex++
+			if values, ok := sliceValues(arg); ok {
+				// 已被括号包裹的 (?) 不再额外加括号
+				wrapped := i > 0 && i+1 < len(runes) && prevNonSpace(runes, i) == '(' && nextNonSpace(runes, i) == ')'
+				if !wrapped {
+					w.WriteString("(")
+				}
+				if len(values) == 0 {
+					w.WriteString("NULL")
+				}
+				for j, v := range values {
+					if j > 0 {
+						w.WriteString(",")
+					}
+					w.writeArg(v)
+				}
+				if !wrapped {
+					w.WriteString(")")
+				}
+				continue
+			}
+			w.writeArg(arg)
+		default:
+			w.WriteRune(r)
+		}
+	}
+	if argIndex < len(c.args) {
+		w.setErr(fmt.Errorf("gosf: too many args for %q", c.query))
+	}
+}
+
+func prevNonSpace(runes []rune, i int) rune {
+	for i--; i >= 0; i-- {
+		if runes[i] != ' ' && runes[i] != '\t' && runes[i] != '\n' {
+			return runes[i]
+		}
+	}
+	return 0
+}
+
+func nextNonSpace(runes []rune, i int) rune {
+	for i++; i < len(runes); i++ {
+		if runes[i] != ' ' && runes[i] != '\t' && runes[i] != '\n' {
+			return runes[i]
+		}
+	}
+	return 0
+}
+
+// Raw 原始SQL条件,使用 ? 作为占位符
+func Raw(query string, args ...interface{}) Cond {
+	return rawCond{query: query, args: args}
+}
+
+// listCond AND / OR 组合条件
+type listCond struct {
+	op    string
+	conds []Cond
+}
+
+func (c listCond) writeTo(w *sqlWriter) {
+	if len(c.conds) == 0 {
+		// 空的AND恒为真,空的OR恒为假
+		if c.op == "AND" {
+			w.WriteString("1=1")
+		} else {
+			w.WriteString("1=0")
+		}
+		return
+	}
+	for i, cond := range c.conds {
+		if i > 0 {
+			w.WriteString(" " + c.op + " ")
+		}
+		if len(c.conds) > 1 && needParens(cond) {
+			w.WriteString("(")
+			cond.writeTo(w)
+			w.WriteString(")")
+		} else {
+			cond.writeTo(w)
+		}
+	}
+}
+
+// needParens 组合条件与原始SQL片段在AND/OR中需要括号
+func needParens(cond Cond) bool {
+	switch c := cond.(type) {
+	case listCond:
+		return len(c.conds) > 1
+	case rawCond:
+		return true
+	}
+	return false
+}
+
+// And 条件同时满足
+func And(conds ...Cond) Cond {
+	return listCond{op: "AND", conds: compactConds(conds)}
+}
+
+// Or 条件满足其一
+func Or(conds ...Cond) Cond {
+	return listCond{op: "OR", conds: compactConds(conds)}
+}
+
+// notCond 条件取反
+type notCond struct {
+	cond Cond
+}
+
+func (c notCond) writeTo(w *sqlWriter) {
+	w.WriteString("NOT (")
+	c.cond.writeTo(w)
+	w.WriteString(")")
+}
+
+// Not 条件取反
+func Not(cond Cond) Cond {
+	return notCond{cond: cond}
+}
+
+// compactConds 去除nil条件
+func compactConds(conds []Cond) []Cond {
+	ret := make([]Cond, 0, len(conds))
+	for _, cond := range conds {
+		if cond != nil {
+			ret = append(ret, cond)
+		}
+	}
+	return ret
+}
+
+// toCond 将 Where 入参转换为条件,支持 Cond、SQL字符串和 map[string]interface{}
+func toCond(query interface{}, values []interface{}) Cond {
+	switch q := query.(type) {
+	case Cond:
+		if len(values) > 0 {
+			return errCond{errors.New("gosf: Where with Cond does not accept extra args")}
+		}
+		return q
+	case string:
+		return Raw(q, values...)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(q))
+		for k := range q {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		conds := make([]Cond, 0, len(keys))
+		for _, k := range keys {
+			if values, ok := sliceValues(q[k]); ok {
+				conds = append(conds, In(k, values...))
+			} else {
+				conds = append(conds, Eq(k, q[k]))
+			}
+		}
+		return And(conds...)
+	}
+	return errCond{fmt.Errorf("gosf: unsupported where type %T", query)}
+}
+
+// errCond 构造阶段的错误,执行时返回
+type errCond struct {
+	err error
+}
+
+func (c errCond) writeTo(w *sqlWriter) {
+	w.setErr(c.err)
+}
+
+// sliceValues slice参数展开,[]byte 与 driver.Valuer 视为单个值
+func sliceValues(arg interface{}) ([]interface{}, bool) {
+	if arg == nil {
+		return nil, false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return nil, false
+	}
+	if _, ok := arg.([]byte); ok {
+		return nil, false
+	}
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+	ret := make([]interface{}, v.Len())
+	for i := range ret {
+		ret[i] = v.Index(i).Interface()
+	}
+	return ret, true
+}
+
+// flattenValues In 的入参只有一个slice时展开
+func flattenValues(values []interface{}) []interface{} {
+	if len(values) == 1 {
+		if ret, ok := sliceValues(values[0]); ok {
+			return ret
+		}
+	}
+	return values
+}
@@ -0,0 +1,79 @@
+package gosf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func renderCond(cond Cond) (string, []interface{}, error) {
+	w := &sqlWriter{}
+	cond.writeTo(w)
+	return w.String(), w.args, w.err
+}
+
+func TestCondRender(t *testing.T) {
+	cases := []struct {
+		cond Cond
+		sql  string
+		args []interface{}
+	}{
+		{Eq("brand", "Apple"), "`brand` = ?", []interface{}{"Apple"}},
+		{Eq("deleted_at", nil), "`deleted_at` IS NULL", nil},
+		{In("id", []int{1, 2, 3}), "`id` IN (?,?,?)", []interface{}{1, 2, 3}},
+		{In("id"), "1=0", nil},
+		{NotIn("u.id", 4, 5), "`u`.`id` NOT IN (?,?)", []interface{}{4, 5}},
+		{Between("price", 1, 9), "`price` BETWEEN ? AND ?", []interface{}{1, 9}},
+		{Like("name", "%a%"), "`name` LIKE ?", []interface{}{"%a%"}},
+		{IsNotNull("operand"), "`operand` IS NOT NULL", nil},
+		{
+			And(Eq("brand", "x"), Or(Eq("a", 1), Raw("b > ? OR c < ?", 2, 3))),
+			"`brand` = ? AND (`a` = ? OR (b > ? OR c < ?))",
+			[]interface{}{"x", 1, 2, 3},
+		},
+		{Not(In("id", 1)), "NOT (`id` IN (?))", []interface{}{1}},
+		{Raw("Name = 'a?b' AND id IN ?", []int64{7, 8}), "Name = 'a?b' AND id IN (?,?)", []interface{}{int64(7), int64(8)}},
+		{Raw("id IN (?)", []string{"a"}), "id IN (?)", []interface{}{"a"}},
+		{Raw("DATE(created_at) = ?", []byte("x")), "DATE(created_at) = ?", []interface{}{[]byte("x")}},
+	}
+	for _, c := range cases {
+		sql, args, err := renderCond(c.cond)
+		if err != nil {
+			t.Fatalf("render %q: %v", c.sql, err)
+		}
+		if sql != c.sql {
+			t.Errorf("got %q, want %q", sql, c.sql)
+		}
+		if len(args) != 0 || len(c.args) != 0 {
+			if !reflect.DeepEqual(args, c.args) {
+				t.Errorf("%q: got args %v, want %v", c.sql, args, c.args)
+			}
+		}
+	}
+}
+
+func TestRawArgCount(t *testing.T) {
+	if _, _, err := renderCond(Raw("a = ? AND b = ?", 1)); err == nil {
+		t.Error("expected error for missing args")
+	}
+	if _, _, err := renderCond(Raw("a = ?", 1, 2)); err == nil {
+		t.Error("expected error for extra args")
+	}
+}
+
+func TestDbPoolWhere(t *testing.T) {
+	p := Query("goods").
+		Where(map[string]interface{}{"status": 1, "brand": "Apple"}).
+		OrWhere("operand = ?", "x").
+		Limit(0)
+	sql, args, err := p.ToSql()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT * FROM `goods` WHERE (`brand` = ? AND `status` = ?) OR (operand = ?)"
+	if sql != want {
+		t.Errorf("got %q, want %q", sql, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"Apple", 1, "x"}) {
+		t.Errorf("unexpected args %v", args)
+	}
+}
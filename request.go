@@ -1,7 +1,23 @@
 package gosf
 
+/**
+HTTP请求
+resp, err := NewRequest().Url("https://example.com/api/user").AddParam("id", "1").GetCtx(ctx)
+resp.StatusCode / resp.Header / resp.Cookies / resp.Body / resp.Duration
+resp.URL       // 跟随重定向后最终的地址
+resp.Redirects // 重定向经过的地址,不含最终地址
+支持 GET、POST、PUT、PATCH、DELETE、HEAD、OPTIONS
+resp, err := NewRequest().Url(u).Body(`{"name":"a"}`).AddHeader("Content-Type", "application/json").PutCtx(ctx)
+resp, err := NewRequest().Url(u).Method("PATCH").DoCtx(ctx)
+GET、HEAD、OPTIONS 或已设置请求内容时参数拼接在URL后,其他方法参数作为表单内容
+请求内容见 JSON、Form、MultipartField、MultipartFile,自动设置 Content-Type
+状态码不是 2xx 时不返回错误,以 resp.IsSuccess() 判断
+旧的 Get、Post、Do 返回 (error, []byte),保留兼容
+默认使用共享的 DefaultClient 复用连接并校验证书,自定义TLS等参数见 NewClient
+*/
 import (
-	"crypto/tls"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,15 +26,47 @@ import (
 	"time"
 )
 
+// maxRedirects 最多跟随的重定向次数
+const maxRedirects = 10
+
+// Response HTTP响应
+type Response struct {
+	StatusCode int            // 状态码
+	Status     string         // 状态,如 "200 OK"
+	Proto      string         // 协议,如 HTTP/1.1
+	Header     http.Header    // 响应头
+	Cookies    []*http.Cookie // 响应设置的cookie
+	Body       []byte         // 响应内容,HEAD 请求为空
+	URL        *url.URL       // 最终请求的地址
+	Redirects  []*url.URL     // 重定向经过的地址,按顺序,不含最终地址
+	Duration   time.Duration  // 从发出请求到读取完响应内容的耗时,重试时为最后一次
+	Attempts   int            // 请求次数,包含重试
+}
+
+// IsSuccess 状态码是否为 2xx
+func (r *Response) IsSuccess() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// String 响应内容
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
 // Request Manage the HTTP GET request parameters
 type Request struct {
 	method  string
 	body    string
+	payload payload // JSON、表单、multipart 内容,优先于 body
 	url     string
 	proxy   Proxy
 	headers []Header
 	params  url.Values
 	timeout int
+	client  *Client
+	retry   *RetryPolicy
+	// 当前请求的中间件,在客户端中间件之后执行
+	middlewares []Middleware
 }
 
 type Header struct {
@@ -48,6 +96,7 @@ func (p *Request) Url(url string) *Request {
 
 func (p *Request) Body(body string) *Request {
 	p.body = body
+	p.payload = nil
 	return p
 }
 
@@ -56,21 +105,68 @@ func (p *Request) Proxy(proxy Proxy) *Request {
 	return p
 }
 
+// Client 指定发送请求的客户端,默认使用 DefaultClient
+func (p *Request) Client(client *Client) *Request {
+	p.client = client
+	return p
+}
+
 func (p *Request) Timeout(timeout int) *Request {
 	p.timeout = timeout
 	return p
 }
 
+// Method 设置请求方法,如 PUT、PATCH
+func (p *Request) Method(method string) *Request {
+	p.method = strings.ToUpper(method)
+	return p
+}
+
 func (p *Request) Get() (error, []byte) {
-	p.method = "GET"
+	p.method = http.MethodGet
 	return p.Do()
 }
 
 func (p *Request) Post() (error, []byte) {
-	p.method = "POST"
+	p.method = http.MethodPost
 	return p.Do()
 }
 
+// GetCtx 发送 GET 请求
+func (p *Request) GetCtx(ctx context.Context) (*Response, error) {
+	return p.Method(http.MethodGet).DoCtx(ctx)
+}
+
+// PostCtx 发送 POST 请求
+func (p *Request) PostCtx(ctx context.Context) (*Response, error) {
+	return p.Method(http.MethodPost).DoCtx(ctx)
+}
+
+// PutCtx 发送 PUT 请求
+func (p *Request) PutCtx(ctx context.Context) (*Response, error) {
+	return p.Method(http.MethodPut).DoCtx(ctx)
+}
+
+// PatchCtx 发送 PATCH 请求
+func (p *Request) PatchCtx(ctx context.Context) (*Response, error) {
+	return p.Method(http.MethodPatch).DoCtx(ctx)
+}
+
+// DeleteCtx 发送 DELETE 请求
+func (p *Request) DeleteCtx(ctx context.Context) (*Response, error) {
+	return p.Method(http.MethodDelete).DoCtx(ctx)
+}
+
+// HeadCtx 发送 HEAD 请求,响应内容为空
+func (p *Request) HeadCtx(ctx context.Context) (*Response, error) {
+	return p.Method(http.MethodHead).DoCtx(ctx)
+}
+
+// OptionsCtx 发送 OPTIONS 请求
+func (p *Request) OptionsCtx(ctx context.Context) (*Response, error) {
+	return p.Method(http.MethodOptions).DoCtx(ctx)
+}
+
 // InitFrom Initialized from another instance
 func (p *Request) InitFrom(reqParams *Request) *Request {
 	if reqParams != nil {
@@ -105,68 +201,148 @@ func (p *Request) AddHeader(key string, value string) *Request {
 	return p
 }
 
+// Do 发送请求,返回响应内容,状态码不是 2xx 时不返回错误
 func (p *Request) Do() (error, []byte) {
-	method := "GET"
-	if InForString([]string{"POST", "GET"}, p.method) {
-		method = p.method
+	resp, err := p.DoCtx(context.Background())
+	if err != nil {
+		fmt.Println("request failed", err)
+		return err, nil
+	}
+	return nil, resp.Body
+}
+
+// DoCtx 发送请求,未设置方法时使用 GET,按重试策略重试
+func (p *Request) DoCtx(ctx context.Context) (*Response, error) {
+	method := p.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	c := p.client
+	if c == nil {
+		c = DefaultClient
+	}
+	policy := p.retryPolicy(c)
+	if policy.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Deadline)
+		defer cancel()
 	}
+	ctx = withRequestIDs(ctx)
+	attempts := 1
+	if p.retryable(method, policy) {
+		attempts = policy.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := p.send(ctx, c, method)
+		if resp != nil {
+			resp.Attempts = attempt
+		}
+		var sendErr *sendError
+		retry := attempt < attempts && !errors.As(err, &sendErr) && policy.shouldRetry(ctx, resp, err)
+		var wait time.Duration
+		if retry {
+			wait = policy.backoff(attempt, resp)
+			// 等待后超过总时长时不再重试
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+				retry = false
+			}
+		}
+		// 首次即成功的请求不记录,只记录需要重试、失败以及重试后的最终结果
+		if attempts > 1 && (retry || attempt > 1 || err != nil) {
+			policy.logAttempt(method, p.url, attempt, resp, err, retry, wait)
+		}
+		if !retry {
+			return resp, err
+		}
+		if sleepErr := sleepCtx(ctx, wait); sleepErr != nil {
+			return resp, err
+		}
+	}
+}
+
+// sendError 发出请求前的错误,不重试
+type sendError struct {
+	err error
+}
+
+func (e *sendError) Error() string { return e.err.Error() }
+
+func (e *sendError) Unwrap() error { return e.err }
+
+// send 发送一次请求
+func (p *Request) send(ctx context.Context, c *Client, method string) (*Response, error) {
 	_url := p.url
-	body := p.body
-	if len(body) == 0 {
-		if method == "GET" {
-			params := p.BuildParams()
-			if len(params) > 0 {
-				if strings.Contains(p.url, "?") {
-					_url = fmt.Sprintf("%s&%s", p.url, params)
-				} else {
-					_url = fmt.Sprintf("%s?%s", p.url, params)
-				}
+	// 已设置请求内容或方法不带内容时,参数拼接在URL后,否则作为表单内容
+	var reader io.Reader
+	contentType := ""
+	if p.payload != nil || len(p.body) > 0 || paramsInQuery(method) {
+		params := p.BuildParams()
+		if len(params) > 0 {
+			if strings.Contains(p.url, "?") {
+				_url = fmt.Sprintf("%s&%s", p.url, params)
+			} else {
+				_url = fmt.Sprintf("%s?%s", p.url, params)
 			}
-		} else {
-			body = p.BuildParams()
 		}
+		if p.payload != nil {
+			body, typ, err := p.payload.open()
+			if err != nil {
+				return nil, &sendError{fmt.Errorf("request body failed: %w", err)}
+			}
+			reader, contentType = body, typ
+		} else if len(p.body) > 0 {
+			reader = strings.NewReader(p.body)
+		}
+	} else if params := p.BuildParams(); len(params) > 0 {
+		reader, contentType = strings.NewReader(params), formContentType
+	}
+	// 超时对每次请求生效,包含读取响应内容的时间
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(p.timeout)*time.Second)
+		defer cancel()
 	}
-	req, err := http.NewRequest(method, _url, strings.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, method, _url, reader)
 	if err != nil {
-		fmt.Println("request failed", err)
-		return err, nil
+		if closer, ok := reader.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		return nil, &sendError{fmt.Errorf("request create failed: %w", err)}
 	}
 
+	// 自动设置的 Content-Type 可以被 AddHeader 覆盖
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 	for _, header := range p.headers {
 		req.Header.Set(header.Key, header.Value)
 	}
 
-	var tr *http.Transport
-
 	// 设置代理
+	var proxy *url.URL
 	if len(p.proxy.Ip) > 0 {
-		proxy := func(_ *http.Request) (*url.URL, error) {
-			rawURL := fmt.Sprintf("http://%s:%s@%s:%d", p.proxy.User, p.proxy.Password, p.proxy.Ip, p.proxy.Port)
-			return url.Parse(rawURL)
-		}
-		// 忽略证书
-		tr = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			Proxy:           proxy,
-		}
-	} else {
-		// 忽略证书
-		tr = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		proxy = &url.URL{
+			Scheme: "http",
+			User:   url.UserPassword(p.proxy.User, p.proxy.Password),
+			Host:   fmt.Sprintf("%s:%d", p.proxy.Ip, p.proxy.Port),
 		}
 	}
-	timeout := 0
-	if p.timeout > 0 {
-		timeout = p.timeout
-	}
+	var redirects []*url.URL
 	client := http.Client{
-		Timeout:   time.Duration(timeout) * time.Second,
-		Transport: tr,
+		Transport: chainMiddlewares(c.transportFor(proxy), c.middlewares, p.middlewares),
+		// 记录重定向经过的地址
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			redirects = append(redirects, via[len(via)-1].URL)
+			return nil
+		},
 	}
+	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		fmt.Println("request do failed", err)
-		return err, nil
+		return nil, fmt.Errorf("request do failed: %w", err)
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -176,10 +352,24 @@ func (p *Request) Do() (error, []byte) {
 	}(resp.Body) // 一定要关闭释放tcp连接
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		fmt.Println("request response body failed", err)
-		return err, nil
+		return nil, fmt.Errorf("request response body failed: %w", err)
 	}
-	return nil, respBody
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Proto:      resp.Proto,
+		Header:     resp.Header,
+		Cookies:    resp.Cookies(),
+		Body:       respBody,
+		URL:        resp.Request.URL,
+		Redirects:  redirects,
+		Duration:   time.Since(start),
+	}, nil
+}
+
+// paramsInQuery 参数是否拼接在URL后
+func paramsInQuery(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
 }
 
 func GetLocation(target string) string {
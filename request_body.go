@@ -0,0 +1,225 @@
+package gosf
+
+/**
+HTTP请求内容,自动设置 Content-Type,AddHeader 设置的值优先
+resp, err := NewRequest().Url(u).JSON(map[string]interface{}{"name": "a"}).PostCtx(ctx)
+resp, err := NewRequest().Url(u).Form(url.Values{"name": {"a"}}).PostCtx(ctx)
+上传文件,文件在发送时打开并以流的方式写入,不会整个读入内存
+resp, err := NewRequest().Url(u).
+	MultipartField("title", "avatar").
+	MultipartFile("file", "/tmp/a.png").
+	MultipartReader("log", "app.log", reader).
+	PostCtx(ctx)
+解析JSON响应,失败时错误中包含状态码与部分响应内容
+var user User
+err = resp.DecodeJSON(&user)
+MultipartReader 传入的 io.Reader 只能读取一次,同一个 Request 再次发送时内容为空
+*/
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 请求内容类型
+const (
+	jsonContentType = "application/json; charset=utf-8"
+	formContentType = "application/x-www-form-urlencoded"
+)
+
+// snippetSize 解析失败时错误中包含的响应内容长度
+const snippetSize = 256
+
+// payload 请求内容,每次发送时调用 open 生成
+type payload interface {
+	open() (io.Reader, string, error)
+	// replayable 能否重复生成,用于重试
+	replayable() bool
+}
+
+// JSON 以 JSON 编码 v 作为请求内容
+func (p *Request) JSON(v interface{}) *Request {
+	p.body = ""
+	p.payload = jsonPayload{v: v}
+	return p
+}
+
+// Form 以表单编码 values 作为请求内容
+func (p *Request) Form(values url.Values) *Request {
+	p.body = ""
+	p.payload = formPayload{values: values}
+	return p
+}
+
+// MultipartField 添加 multipart 普通字段
+func (p *Request) MultipartField(name, value string) *Request {
+	return p.addPart(multipartPart{field: name, value: value})
+}
+
+// MultipartFile 添加 multipart 文件,发送时从 path 读取,文件名取 path 的最后一段
+func (p *Request) MultipartFile(field, path string) *Request {
+	return p.addPart(multipartPart{field: field, filename: filepath.Base(path), path: path})
+}
+
+// MultipartReader 添加 multipart 文件,内容从 r 读取
+func (p *Request) MultipartReader(field, filename string, r io.Reader) *Request {
+	return p.addPart(multipartPart{field: field, filename: filename, reader: r})
+}
+
+// addPart 追加 multipart 内容,已设置其他类型的内容时替换
+func (p *Request) addPart(part multipartPart) *Request {
+	p.body = ""
+	m, ok := p.payload.(*multipartPayload)
+	if !ok {
+		m = &multipartPayload{}
+		p.payload = m
+	}
+	m.parts = append(m.parts, part)
+	return p
+}
+
+// jsonPayload JSON 内容
+type jsonPayload struct {
+	v interface{}
+}
+
+func (j jsonPayload) open() (io.Reader, string, error) {
+	data, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, "", fmt.Errorf("json encode: %w", err)
+	}
+	return bytes.NewReader(data), jsonContentType, nil
+}
+
+func (j jsonPayload) replayable() bool { return true }
+
+// formPayload 表单内容
+type formPayload struct {
+	values url.Values
+}
+
+func (f formPayload) open() (io.Reader, string, error) {
+	return strings.NewReader(f.values.Encode()), formContentType, nil
+}
+
+func (f formPayload) replayable() bool { return true }
+
+// multipartPart multipart 的一个字段,path 与 reader 都为空时为普通字段
+type multipartPart struct {
+	field    string
+	value    string
+	filename string
+	path     string
+	reader   io.Reader
+}
+
+// multipartPayload multipart 内容,按添加顺序写入
+type multipartPayload struct {
+	parts []multipartPart
+}
+
+func (m *multipartPayload) open() (io.Reader, string, error) {
+	// 先打开全部文件,出错时直接返回
+	files := make([]*os.File, len(m.parts))
+	closeFiles := func() {
+		for _, f := range files {
+			if f != nil {
+				_ = f.Close()
+			}
+		}
+	}
+	for i, part := range m.parts {
+		if part.path == "" {
+			continue
+		}
+		f, err := os.Open(part.path)
+		if err != nil {
+			closeFiles()
+			return nil, "", err
+		}
+		files[i] = f
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		defer closeFiles()
+		err := m.write(mw, files)
+		if err == nil {
+			err = mw.Close()
+		}
+		// 请求结束后关闭了读取端时写入出错,错误传给读取端
+		_ = pw.CloseWithError(err)
+	}()
+	return pr, mw.FormDataContentType(), nil
+}
+
+// replayable 包含 MultipartReader 时不能重复发送
+func (m *multipartPayload) replayable() bool {
+	for _, part := range m.parts {
+		if part.reader != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// write 逐个写入字段与文件
+func (m *multipartPayload) write(mw *multipart.Writer, files []*os.File) error {
+	for i, part := range m.parts {
+		if files[i] == nil && part.reader == nil {
+			if err := mw.WriteField(part.field, part.value); err != nil {
+				return err
+			}
+			continue
+		}
+		w, err := mw.CreatePart(filePartHeader(part.field, part.filename))
+		if err != nil {
+			return err
+		}
+		src := part.reader
+		if files[i] != nil {
+			src = files[i]
+		}
+		if _, err = io.Copy(w, src); err != nil {
+			return fmt.Errorf("multipart %s: %w", part.field, err)
+		}
+	}
+	return nil
+}
+
+// filePartHeader 文件字段的头,按扩展名设置 Content-Type
+func filePartHeader(field, filename string) textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": field, "filename": filename}))
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h.Set("Content-Type", contentType)
+	return h
+}
+
+// DecodeJSON 以 JSON 解析响应内容到 v
+func (r *Response) DecodeJSON(v interface{}) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return fmt.Errorf("decode json response (status %d): %w, body: %s", r.StatusCode, err, r.snippet())
+	}
+	return nil
+}
+
+// snippet 截取部分响应内容用于错误信息
+func (r *Response) snippet() string {
+	if len(r.Body) <= snippetSize {
+		return string(r.Body)
+	}
+	return string(r.Body[:snippetSize]) + "..."
+}
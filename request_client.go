@@ -0,0 +1,217 @@
+package gosf
+
+/**
+HTTP客户端,复用连接,NewRequest 使用共享的 DefaultClient
+client, err := NewClient(ClientOptions{
+	MaxIdleConnsPerHost: 50,
+	CAFile:              "/etc/app/ca.pem",
+	CertFile:            "/etc/app/client.pem", // 双向认证
+	KeyFile:             "/etc/app/client.key",
+})
+resp, err := client.NewRequest().Url(u).GetCtx(ctx)
+默认校验证书,只有设置 InsecureSkipVerify 才忽略证书
+Request 设置的代理按地址复用连接,每个代理地址一个连接池
+*/
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// 客户端默认参数
+const (
+	clientMaxIdleConns        = 100
+	clientMaxIdleConnsPerHost = 10
+	clientIdleConnTimeout     = 90 * time.Second
+	clientTLSHandshakeTimeout = 10 * time.Second
+	clientDialTimeout         = 30 * time.Second
+	clientKeepAlive           = 30 * time.Second
+)
+
+// DefaultClient NewRequest 使用的共享客户端
+var DefaultClient = MustNewClient(ClientOptions{})
+
+// ClientOptions HTTP客户端配置
+type ClientOptions struct {
+	MaxIdleConns        int           // 全部主机最大空闲连接数,默认100
+	MaxIdleConnsPerHost int           // 每个主机最大空闲连接数,默认10
+	MaxConnsPerHost     int           // 每个主机最大连接数,默认不限制
+	IdleConnTimeout     time.Duration // 空闲连接最长保留时间,默认90秒
+	DialTimeout         time.Duration // 建立连接超时时间,默认30秒
+	TLSHandshakeTimeout time.Duration // TLS握手超时时间,默认10秒
+	DisableHTTP2        bool          // 禁用HTTP/2
+	DisableKeepAlives   bool          // 禁用长连接
+
+	CAFile             string                                // 额外信任的CA证书文件,PEM格式,追加到系统证书
+	RootCAs            *x509.CertPool                        // 信任的CA证书,设置后不使用系统证书与 CAFile
+	CertFile           string                                // 客户端证书文件,用于双向认证
+	KeyFile            string                                // 客户端私钥文件
+	Certificates       []tls.Certificate                     // 客户端证书,与 CertFile 同时设置时合并
+	InsecureSkipVerify bool                                  // 不校验服务端证书,只用于测试环境
+	TLSConfig          *tls.Config                           // 完整的TLS配置,设置后忽略以上TLS参数
+	Proxy              func(*http.Request) (*url.URL, error) // 默认代理,默认读取环境变量
+
+	Retry       RetryPolicy  // 默认重试策略,Request.Retry 覆盖
+	Middlewares []Middleware // 中间件,按顺序包裹每次请求
+}
+
+// Client HTTP客户端,并发安全
+type Client struct {
+	transport   *http.Transport
+	retry       RetryPolicy
+	middlewares []Middleware
+	proxies     *proxyTransports // 按代理地址复用的连接池,Use 返回的客户端共用
+}
+
+// proxyTransports 按代理地址复用的连接池
+type proxyTransports struct {
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+// NewClient 创建HTTP客户端
+func NewClient(opts ClientOptions) (*Client, error) {
+	tlsConfig, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxIdleConns <= 0 {
+		opts.MaxIdleConns = clientMaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = clientMaxIdleConnsPerHost
+	}
+	if opts.IdleConnTimeout <= 0 {
+		opts.IdleConnTimeout = clientIdleConnTimeout
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = clientDialTimeout
+	}
+	if opts.TLSHandshakeTimeout <= 0 {
+		opts.TLSHandshakeTimeout = clientTLSHandshakeTimeout
+	}
+	if opts.Proxy == nil {
+		opts.Proxy = http.ProxyFromEnvironment
+	}
+	dialer := &net.Dialer{Timeout: opts.DialTimeout, KeepAlive: clientKeepAlive}
+	tr := &http.Transport{
+		Proxy:               opts.Proxy,
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        opts.MaxIdleConns,
+		MaxIdleConnsPerHost: opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:     opts.MaxConnsPerHost,
+		IdleConnTimeout:     opts.IdleConnTimeout,
+		TLSHandshakeTimeout: opts.TLSHandshakeTimeout,
+		DisableKeepAlives:   opts.DisableKeepAlives,
+		TLSClientConfig:     tlsConfig,
+		// 自定义了 TLSClientConfig 时需要显式开启HTTP/2
+		ForceAttemptHTTP2:     !opts.DisableHTTP2,
+		ExpectContinueTimeout: time.Second,
+	}
+	if opts.DisableHTTP2 {
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return &Client{
+		transport:   tr,
+		retry:       opts.Retry,
+		middlewares: append([]Middleware(nil), opts.Middlewares...),
+		proxies:     &proxyTransports{transports: make(map[string]*http.Transport)},
+	}, nil
+}
+
+// MustNewClient 创建HTTP客户端,配置错误时 panic
+func MustNewClient(opts ClientOptions) *Client {
+	c, err := NewClient(opts)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// tlsConfig 按配置生成TLS配置
+func (o ClientOptions) tlsConfig() (*tls.Config, error) {
+	if o.TLSConfig != nil {
+		return o.TLSConfig.Clone(), nil
+	}
+	cfg := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipVerify,
+		RootCAs:            o.RootCAs,
+		Certificates:       append([]tls.Certificate(nil), o.Certificates...),
+	}
+	if o.CAFile != "" && o.RootCAs == nil {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in ca file %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, errors.New("client certificate requires both CertFile and KeyFile")
+		}
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	return cfg, nil
+}
+
+// NewRequest 创建使用当前客户端的请求
+func (c *Client) NewRequest() *Request {
+	return NewRequest().Client(c)
+}
+
+// Use 返回追加了中间件的客户端,与原客户端共用连接池
+func (c *Client) Use(middlewares ...Middleware) *Client {
+	q := *c
+	q.middlewares = append(c.middlewares[:len(c.middlewares):len(c.middlewares)], middlewares...)
+	return &q
+}
+
+// Transport 底层连接池,可用于 http.Client 等其他场景
+func (c *Client) Transport() *http.Transport {
+	return c.transport
+}
+
+// CloseIdleConnections 关闭全部空闲连接
+func (c *Client) CloseIdleConnections() {
+	c.transport.CloseIdleConnections()
+	c.proxies.mu.Lock()
+	defer c.proxies.mu.Unlock()
+	for _, tr := range c.proxies.transports {
+		tr.CloseIdleConnections()
+	}
+}
+
+// transportFor 指定代理时使用该代理地址对应的连接池
+func (c *Client) transportFor(proxy *url.URL) *http.Transport {
+	if proxy == nil {
+		return c.transport
+	}
+	key := proxy.String()
+	c.proxies.mu.Lock()
+	defer c.proxies.mu.Unlock()
+	tr, ok := c.proxies.transports[key]
+	if !ok {
+		tr = c.transport.Clone()
+		tr.Proxy = http.ProxyURL(proxy)
+		c.proxies.transports[key] = tr
+	}
+	return tr
+}
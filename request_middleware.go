@@ -0,0 +1,458 @@
+package gosf
+
+/**
+客户端中间件,包裹每次请求,重试与重定向的每一次都会经过
+client := DefaultClient.Use(
+	RequestID(""),
+	BearerAuth(token),
+	HMACSign(SignOptions{KeyID: "app1", Secret: secret}),
+	LogRequests(LogOptions{Logger: app.Log(), LogBody: true, RedactFields: []string{"password"}}),
+)
+resp, err := client.NewRequest().Url(u).JSON(body).PostCtx(ctx)
+单个请求追加中间件
+resp, err := NewRequest().Url(u).Use(BasicAuth("user", "pwd")).GetCtx(ctx)
+中间件按添加顺序由外向内执行,客户端的中间件在请求的中间件之前
+自定义中间件
+func Timing(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next.RoundTrip(req)
+		fmt.Println(req.URL, time.Since(start))
+		return resp, err
+	})
+}
+中间件不能修改传入的请求,需要设置请求头时先 req.Clone
+SetHeaders、BearerAuth、BasicAuth、HMACSign 只作用于原始请求的主机,重定向到其他主机时不再添加
+*/
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 中间件默认参数
+const (
+	defaultRequestIDHeader = "X-Request-Id"
+	defaultSignatureHeader = "X-Signature"
+	defaultTimestampHeader = "X-Timestamp"
+	defaultKeyIDHeader     = "X-Key-Id"
+	defaultLogBodySize     = 1024
+	redacted               = "***"
+)
+
+// defaultRedactHeaders 日志中默认隐藏的请求头与响应头
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", defaultSignatureHeader}
+
+// Middleware 客户端中间件,包裹下一层的 RoundTripper
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc 函数形式的 http.RoundTripper
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip 实现 http.RoundTripper
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Use 当前请求追加中间件,在客户端的中间件之后执行
+func (p *Request) Use(middlewares ...Middleware) *Request {
+	p.middlewares = append(p.middlewares, middlewares...)
+	return p
+}
+
+// chainMiddlewares 按顺序包裹 tr,第一个中间件在最外层
+func chainMiddlewares(tr http.RoundTripper, groups ...[]Middleware) http.RoundTripper {
+	var all []Middleware
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	for i := len(all) - 1; i >= 0; i-- {
+		tr = all[i](tr)
+	}
+	return tr
+}
+
+// sameHost 是否与原始请求的主机相同,重定向到其他主机时不添加凭证与签名
+func sameHost(req *http.Request) bool {
+	first := req
+	for first.Response != nil && first.Response.Request != nil {
+		first = first.Response.Request
+	}
+	return first.URL.Host == req.URL.Host
+}
+
+// withHeaders 复制请求并设置请求头,重定向到其他主机时不设置
+func withHeaders(next http.RoundTripper, set func(h http.Header)) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if !sameHost(req) {
+			return next.RoundTrip(req)
+		}
+		req = req.Clone(req.Context())
+		set(req.Header)
+		return next.RoundTrip(req)
+	})
+}
+
+// SetHeaders 为每个请求设置固定的请求头,覆盖已有的值
+func SetHeaders(headers map[string]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withHeaders(next, func(h http.Header) {
+			for k, v := range headers {
+				h.Set(k, v)
+			}
+		})
+	}
+}
+
+// BearerAuth 设置 Authorization: Bearer token
+func BearerAuth(token string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return withHeaders(next, func(h http.Header) {
+			h.Set("Authorization", "Bearer "+token)
+		})
+	}
+}
+
+// BasicAuth 设置 HTTP Basic 认证
+func BasicAuth(user, password string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !sameHost(req) {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			req.SetBasicAuth(user, password)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// RequestID 请求没有 header 时生成随机的请求ID,header 为空时使用 X-Request-Id
+// 同一次 DoCtx 的重试与重定向使用相同的ID
+func RequestID(header string) Middleware {
+	if header == "" {
+		header = defaultRequestIDHeader
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) != "" {
+				return next.RoundTrip(req)
+			}
+			id, err := requestIDFor(req.Context(), header)
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set(header, id)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// requestIDKey 上下文中保存请求ID的键
+type requestIDKey struct{}
+
+// requestIDs DoCtx 中已生成的请求ID,按请求头名称保存
+type requestIDs struct {
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+// withRequestIDs 每次 DoCtx 在上下文中保存请求ID,重试时复用
+func withRequestIDs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, &requestIDs{ids: make(map[string]string)})
+}
+
+// requestIDFor 获取本次请求的ID,不是通过 DoCtx 发送时每次生成新的ID
+func requestIDFor(ctx context.Context, header string) (string, error) {
+	saved, ok := ctx.Value(requestIDKey{}).(*requestIDs)
+	if !ok {
+		return newRequestID()
+	}
+	saved.mu.Lock()
+	defer saved.mu.Unlock()
+	if id, ok := saved.ids[header]; ok {
+		return id, nil
+	}
+	id, err := newRequestID()
+	if err != nil {
+		return "", err
+	}
+	saved.ids[header] = id
+	return id, nil
+}
+
+// newRequestID 32位十六进制随机字符串
+func newRequestID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("request id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SignOptions HMAC 签名配置
+type SignOptions struct {
+	Secret          []byte           // 签名密钥
+	KeyID           string           // 密钥标识,不为空时写入 KeyIDHeader
+	KeyIDHeader     string           // 默认 X-Key-Id
+	SignatureHeader string           // 默认 X-Signature
+	TimestampHeader string           // 默认 X-Timestamp,值为秒级时间戳
+	Hash            func() hash.Hash // 默认 sha256.New
+	Now             func() time.Time // 默认 time.Now
+}
+
+// HMACSign 对请求签名,签名内容见 SignString,结果为十六进制
+// 签名需要读取完整的请求内容,multipart 等流式内容会读入内存
+func HMACSign(opts SignOptions) Middleware {
+	if opts.KeyIDHeader == "" {
+		opts.KeyIDHeader = defaultKeyIDHeader
+	}
+	if opts.SignatureHeader == "" {
+		opts.SignatureHeader = defaultSignatureHeader
+	}
+	if opts.TimestampHeader == "" {
+		opts.TimestampHeader = defaultTimestampHeader
+	}
+	if opts.Hash == nil {
+		opts.Hash = sha256.New
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !sameHost(req) {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			body, err := bufferBody(req)
+			if err != nil {
+				return nil, fmt.Errorf("sign request: %w", err)
+			}
+			timestamp := strconv.FormatInt(opts.Now().Unix(), 10)
+			mac := hmac.New(opts.Hash, opts.Secret)
+			mac.Write([]byte(SignString(req.Method, req.URL.RequestURI(), timestamp, body)))
+			req.Header.Set(opts.TimestampHeader, timestamp)
+			req.Header.Set(opts.SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+			if opts.KeyID != "" {
+				req.Header.Set(opts.KeyIDHeader, opts.KeyID)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// SignString 签名内容,服务端校验时按相同规则生成
+// 方法\n路径与查询参数\n时间戳\n请求内容的 sha256 十六进制
+func SignString(method, requestURI, timestamp string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return method + "\n" + requestURI + "\n" + timestamp + "\n" + hex.EncodeToString(sum[:])
+}
+
+// bufferBody 读取请求内容,并替换为可重复读取的内容,req 需是复制后的请求
+func bufferBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.ContentLength = int64(len(body))
+	return body, nil
+}
+
+// LogOptions 请求日志配置
+type LogOptions struct {
+	Logger        *Logger  // 为空时输出到标准输出
+	RedactHeaders []string // 隐藏值的请求头与响应头,为空时隐藏 Authorization、Cookie、Set-Cookie 等
+	RedactFields  []string // 隐藏值的 JSON、表单字段与URL参数,JSON 按字段名匹配任意层级
+	LogBody       bool     // 记录请求与响应内容
+	MaxBody       int      // 记录的内容最大字节数,默认1024
+}
+
+// LogRequests 记录每次请求与响应,请求失败时记录为错误
+func LogRequests(opts LogOptions) Middleware {
+	if opts.RedactHeaders == nil {
+		opts.RedactHeaders = defaultRedactHeaders
+	}
+	if opts.MaxBody <= 0 {
+		opts.MaxBody = defaultLogBodySize
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			v := []interface{}{"http", req.Method, opts.url(req.URL), "headers:" + opts.headers(req.Header)}
+			if opts.LogBody {
+				req = req.Clone(req.Context())
+				body, err := bufferBody(req)
+				if err != nil {
+					return nil, err
+				}
+				v = append(v, "body:"+opts.body(req.Header.Get("Content-Type"), body))
+			}
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			v = append(v, time.Since(start).String())
+			if err != nil {
+				opts.log(true, append(v, err)...)
+				return nil, err
+			}
+			v = append(v, resp.Status, "response headers:"+opts.headers(resp.Header))
+			if opts.LogBody {
+				body, err := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				resp.Body = io.NopCloser(bytes.NewReader(body))
+				if err != nil {
+					opts.log(true, append(v, err)...)
+					return resp, nil
+				}
+				v = append(v, "response body:"+opts.body(resp.Header.Get("Content-Type"), body))
+			}
+			opts.log(false, v...)
+			return resp, nil
+		})
+	}
+}
+
+// log 输出日志
+func (opts LogOptions) log(failed bool, v ...interface{}) {
+	if opts.Logger == nil {
+		fmt.Println(v...)
+		return
+	}
+	if failed {
+		opts.Logger.Error(v...)
+		return
+	}
+	opts.Logger.Info(v...)
+}
+
+// url 隐藏敏感的URL参数
+func (opts LogOptions) url(u *url.URL) string {
+	if len(opts.RedactFields) == 0 || u.RawQuery == "" {
+		return u.String()
+	}
+	// 无法解析的参数丢弃,避免原样输出敏感值
+	values, _ := url.ParseQuery(u.RawQuery)
+	for k := range values {
+		if opts.redactField(k) {
+			values[k] = []string{redacted}
+		}
+	}
+	q := *u
+	q.RawQuery = values.Encode()
+	return q.String()
+}
+
+// headers 隐藏敏感值后按名称输出请求头
+func (opts LogOptions) headers(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		value := strings.Join(h[k], ",")
+		if opts.redactHeader(k) {
+			value = redacted
+		}
+		items[i] = k + "=" + value
+	}
+	return strings.Join(items, "; ")
+}
+
+func (opts LogOptions) redactHeader(key string) bool {
+	for _, item := range opts.RedactHeaders {
+		if strings.EqualFold(item, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (opts LogOptions) redactField(key string) bool {
+	for _, item := range opts.RedactFields {
+		if strings.EqualFold(item, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// body 隐藏敏感字段并截断内容
+func (opts LogOptions) body(contentType string, body []byte) string {
+	if len(opts.RedactFields) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			var v interface{}
+			if json.Unmarshal(body, &v) == nil {
+				if data, err := json.Marshal(opts.redactJSON(v)); err == nil {
+					body = data
+				}
+			}
+		case mediaType == formContentType:
+			if values, err := url.ParseQuery(string(body)); err == nil {
+				for k := range values {
+					if opts.redactField(k) {
+						values[k] = []string{redacted}
+					}
+				}
+				body = []byte(values.Encode())
+			}
+		}
+	}
+	if len(body) > opts.MaxBody {
+		return string(body[:opts.MaxBody]) + "..."
+	}
+	return string(body)
+}
+
+// redactJSON 递归隐藏 JSON 中的敏感字段
+func (opts LogOptions) redactJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, item := range x {
+			if opts.redactField(k) {
+				x[k] = redacted
+				continue
+			}
+			x[k] = opts.redactJSON(item)
+		}
+	case []interface{}:
+		for i, item := range x {
+			x[i] = opts.redactJSON(item)
+		}
+	}
+	return v
+}
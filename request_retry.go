@@ -0,0 +1,189 @@
+package gosf
+
+/**
+请求重试,连接错误与 5xx、429 响应时按指数退避加随机抖动重试,响应带 Retry-After 时按其等待,最长 MaxRetryAfter
+resp, err := NewRequest().Url(u).Retry(RetryPolicy{MaxAttempts: 3, Deadline: 30 * time.Second}).GetCtx(ctx)
+客户端统一设置,Request.Retry 覆盖
+client, _ := NewClient(ClientOptions{Retry: RetryPolicy{MaxAttempts: 3, Logger: app.Log()}})
+只重试幂等方法 GET、HEAD、OPTIONS、PUT、DELETE、TRACE 及带 Idempotency-Key 头的请求
+POST、PATCH 需要设置 RetryNonIdempotent,MultipartReader 的内容无法重复读取,不重试
+重试用完仍为 5xx、429 时返回最后一次的响应,不返回错误
+Timeout 对每次请求生效,Deadline 限制包含等待在内的总时长
+允许重试时 Logger 记录需要重试与失败的请求以及重试后的最终结果,首次即成功的请求不记录
+*/
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 重试默认参数
+const (
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// RetryPolicy 重试策略,零值不重试
+type RetryPolicy struct {
+	MaxAttempts        int                                  // 最多请求次数,包含第一次,小于2时不重试
+	MinBackoff         time.Duration                        // 第一次重试前的等待时间,默认100毫秒
+	MaxBackoff         time.Duration                        // 最长等待时间,默认5秒
+	MaxRetryAfter      time.Duration                        // 按 Retry-After 等待的最长时间,默认同 MaxBackoff
+	Deadline           time.Duration                        // 包含重试在内的总时长,默认不限制
+	RetryNonIdempotent bool                                 // 允许重试 POST、PATCH 等非幂等请求
+	RetryIf            func(resp *Response, err error) bool // 自定义是否重试,默认连接错误与 5xx、429
+	Logger             *Logger                              // 记录重试与失败的请求,首次即成功的请求不记录,为空时输出到标准输出
+}
+
+// Retry 设置当前请求的重试策略,覆盖客户端的设置
+func (p *Request) Retry(policy RetryPolicy) *Request {
+	p.retry = &policy
+	return p
+}
+
+// retryPolicy 当前请求生效的重试策略
+func (p *Request) retryPolicy(c *Client) RetryPolicy {
+	policy := c.retry
+	if p.retry != nil {
+		policy = *p.retry
+	}
+	if policy.MinBackoff <= 0 {
+		policy.MinBackoff = defaultMinBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultMaxBackoff
+	}
+	if policy.MaxBackoff < policy.MinBackoff {
+		policy.MaxBackoff = policy.MinBackoff
+	}
+	if policy.MaxRetryAfter <= 0 {
+		policy.MaxRetryAfter = policy.MaxBackoff
+	}
+	return policy
+}
+
+// retryable 请求是否允许重试
+func (p *Request) retryable(method string, policy RetryPolicy) bool {
+	if policy.MaxAttempts < 2 {
+		return false
+	}
+	if p.payload != nil && !p.payload.replayable() {
+		return false
+	}
+	return policy.RetryNonIdempotent || isIdempotent(method) || p.header("Idempotency-Key") != ""
+}
+
+// header 已添加的请求头
+func (p *Request) header(key string) string {
+	key = http.CanonicalHeaderKey(key)
+	value := ""
+	for _, h := range p.headers {
+		if http.CanonicalHeaderKey(h.Key) == key {
+			value = h.Value
+		}
+	}
+	return value
+}
+
+// isIdempotent 是否为幂等方法
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// shouldRetry 按响应或错误判断是否需要重试
+func (policy RetryPolicy) shouldRetry(ctx context.Context, resp *Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if policy.RetryIf != nil {
+		return policy.RetryIf(resp, err)
+	}
+	// 调用方取消或总时长已到时上面已返回,单次请求超时可以重试
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// backoff 第 attempt 次请求失败后的等待时间,指数增长并加随机抖动,Retry-After 优先,不超过 MaxRetryAfter
+func (policy RetryPolicy) backoff(attempt int, resp *Response) time.Duration {
+	if resp != nil {
+		if wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if wait > policy.MaxRetryAfter {
+				wait = policy.MaxRetryAfter
+			}
+			return wait
+		}
+	}
+	wait := policy.MinBackoff
+	for i := 1; i < attempt && wait < policy.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > policy.MaxBackoff {
+		wait = policy.MaxBackoff
+	}
+	// 在 [wait/2, wait) 之间随机
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(wait-half)+1))
+}
+
+// retryAfter 解析 Retry-After,支持秒数与HTTP日期
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := at.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+// logAttempt 记录一次请求的结果,retry 为是否在等待 wait 后重试
+func (policy RetryPolicy) logAttempt(method, target string, attempt int, resp *Response, err error, retry bool, wait time.Duration) {
+	v := []interface{}{"request attempt", method, target, fmt.Sprintf("attempt %d/%d", attempt, policy.MaxAttempts)}
+	if err != nil {
+		v = append(v, err)
+	} else {
+		v = append(v, resp.Status)
+	}
+	if retry {
+		v = append(v, "retry in "+wait.String())
+	}
+	if policy.Logger == nil {
+		fmt.Println(v...)
+		return
+	}
+	policy.Logger.Info(v...)
+}
+
+// sleepCtx 等待 d,ctx 结束时提前返回错误
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,339 @@
+package gosf
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestMethods(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new?"+r.URL.RawQuery, http.StatusFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1"})
+		w.Header().Set("X-Method", r.Method)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(r.URL.RawQuery + "|" + string(body)))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	resp, err := NewRequest().Url(srv.URL+"/old").AddParam("id", "1").GetCtx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || !resp.IsSuccess() || resp.String() != "id=1|" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, resp.Body)
+	}
+	if resp.URL.Path != "/new" || len(resp.Redirects) != 1 || resp.Redirects[0].Path != "/old" {
+		t.Fatalf("unexpected redirects %v -> %v", resp.Redirects, resp.URL)
+	}
+	if len(resp.Cookies) != 1 || resp.Cookies[0].Name != "sid" {
+		t.Fatalf("unexpected cookies %v", resp.Cookies)
+	}
+
+	for _, method := range []string{"PUT", "PATCH", "DELETE", "OPTIONS", "HEAD"} {
+		resp, err = NewRequest().Url(srv.URL).Method(method).AddParam("a", "b").DoCtx(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("X-Method"); got != method {
+			t.Errorf("method %s sent as %s", method, got)
+		}
+	}
+	if resp.String() != "" {
+		t.Errorf("HEAD response body %q", resp.Body)
+	}
+	resp, _ = NewRequest().Url(srv.URL).AddParam("a", "b").PutCtx(ctx)
+	if resp.String() != "|a=b" {
+		t.Errorf("PUT params should be sent as body, got %q", resp.Body)
+	}
+}
+
+func TestRequestBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ret := map[string]string{"type": r.Header.Get("Content-Type"), "query": r.URL.RawQuery}
+		if strings.HasPrefix(ret["type"], "multipart/") {
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f, header, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(f)
+			ret["body"] = r.FormValue("title") + "|" + header.Filename + "|" + string(data)
+		} else {
+			data, _ := io.ReadAll(r.Body)
+			ret["body"] = string(data)
+		}
+		_ = json.NewEncoder(w).Encode(ret)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	var ret map[string]string
+
+	resp, err := NewRequest().Url(srv.URL).AddParam("id", "1").JSON(map[string]int{"a": 1}).PostCtx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = resp.DecodeJSON(&ret); err != nil {
+		t.Fatal(err)
+	}
+	if ret["type"] != jsonContentType || ret["body"] != `{"a":1}` || ret["query"] != "id=1" {
+		t.Errorf("unexpected json request %v", ret)
+	}
+
+	resp, _ = NewRequest().Url(srv.URL).Form(url.Values{"a": {"1"}}).PostCtx(ctx)
+	_ = resp.DecodeJSON(&ret)
+	if ret["type"] != formContentType || ret["body"] != "a=1" {
+		t.Errorf("unexpected form request %v", ret)
+	}
+
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err = os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = NewRequest().Url(srv.URL).MultipartField("title", "t").MultipartFile("file", path).PostCtx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = resp.DecodeJSON(&ret); err != nil {
+		t.Fatal(err)
+	}
+	if ret["body"] != "t|a.txt|hello" {
+		t.Errorf("unexpected multipart request %v", ret)
+	}
+
+	resp = &Response{StatusCode: 502, Body: []byte("<html>bad gateway</html>")}
+	if err = resp.DecodeJSON(&ret); err == nil || !strings.Contains(err.Error(), "bad gateway") {
+		t.Errorf("expected error with body snippet, got %v", err)
+	}
+}
+
+func TestClientTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := NewRequest().Url(srv.URL).GetCtx(ctx); err == nil {
+		t.Fatal("expected certificate error with default client")
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	for _, opts := range []ClientOptions{{RootCAs: pool}, {InsecureSkipVerify: true}} {
+		client, err := NewClient(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.NewRequest().Url(srv.URL).GetCtx(ctx)
+		if err != nil || resp.String() != "ok" {
+			t.Fatalf("resp=%v err=%v", resp, err)
+		}
+		client.CloseIdleConnections()
+	}
+	if _, err := NewClient(ClientOptions{CertFile: "client.pem"}); err == nil {
+		t.Error("expected error without KeyFile")
+	}
+}
+
+func TestRequestRetry(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1)%3 != 0 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	var buf bytes.Buffer
+	logger := &Logger{InfoLogger: log.New(&buf, "", 0)}
+	policy := RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, Logger: logger}
+
+	resp, err := NewRequest().Url(srv.URL).Retry(policy).GetCtx(ctx)
+	if err != nil || resp.String() != "ok" || resp.Attempts != 3 {
+		t.Fatalf("resp=%+v err=%v", resp, err)
+	}
+	// 重试的请求每次都记录,包括最后成功的一次
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "attempt 1/3 503 Service Unavailable retry in 0s") ||
+		!strings.Contains(lines[2], "attempt 3/3 200 OK") || strings.Contains(lines[2], "retry in") {
+		t.Errorf("unexpected retry log %q", buf.String())
+	}
+	// 首次即成功的请求不记录
+	buf.Reset()
+	atomic.StoreInt32(&hits, 2)
+	if resp, err = NewRequest().Url(srv.URL).Retry(policy).GetCtx(ctx); err != nil || resp.Attempts != 1 || buf.Len() != 0 {
+		t.Fatalf("first attempt success: resp=%+v err=%v log=%q", resp, err, buf.String())
+	}
+	resp, err = NewRequest().Url(srv.URL).Retry(policy).PostCtx(ctx)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable || resp.Attempts != 1 {
+		t.Fatalf("POST should not be retried: resp=%+v err=%v", resp, err)
+	}
+	resp, err = NewRequest().Url(srv.URL).Retry(policy).AddHeader("Idempotency-Key", "k1").PostCtx(ctx)
+	if err != nil || resp.Attempts != 2 || resp.String() != "ok" {
+		t.Fatalf("POST with Idempotency-Key: resp=%+v err=%v", resp, err)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if wait, ok := retryAfter("3", now); !ok || wait != 3*time.Second {
+		t.Errorf("retryAfter seconds = %v %v", wait, ok)
+	}
+	if wait, ok := retryAfter(now.Add(time.Minute).Format(http.TimeFormat), now); !ok || wait != time.Minute {
+		t.Errorf("retryAfter date = %v %v", wait, ok)
+	}
+
+	// Retry-After 不超过 MaxRetryAfter,默认同 MaxBackoff
+	limited := &Response{Header: http.Header{"Retry-After": {"3600"}}}
+	if wait := NewRequest().retryPolicy(DefaultClient).backoff(1, limited); wait != defaultMaxBackoff {
+		t.Errorf("default Retry-After cap = %v", wait)
+	}
+	if wait := NewRequest().Retry(RetryPolicy{MaxRetryAfter: time.Minute}).retryPolicy(DefaultClient).backoff(1, limited); wait != time.Minute {
+		t.Errorf("MaxRetryAfter cap = %v", wait)
+	}
+}
+
+func TestClientMiddlewares(t *testing.T) {
+	secret := []byte("secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(SignString(r.Method, r.URL.RequestURI(), r.Header.Get("X-Timestamp"), body)))
+		if hex.EncodeToString(mac.Sum(nil)) != r.Header.Get("X-Signature") || r.Header.Get("X-Key-Id") != "app1" {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer tk" || len(r.Header.Get("X-Request-Id")) != 32 {
+			http.Error(w, "missing headers", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"new","user":{"name":"a"}}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	logger := &Logger{InfoLogger: log.New(&buf, "", 0), ErrorLogger: log.New(&buf, "", 0)}
+	client := DefaultClient.Use(
+		RequestID(""),
+		BearerAuth("tk"),
+		LogRequests(LogOptions{Logger: logger, LogBody: true, RedactFields: []string{"password", "token"}}),
+	)
+	resp, err := client.NewRequest().Url(srv.URL + "/login?a=1&token=secret-token").
+		Use(HMACSign(SignOptions{KeyID: "app1", Secret: secret})).
+		JSON(map[string]string{"user": "a", "password": "p"}).
+		PostCtx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, resp.Body)
+	}
+	out := buf.String()
+	if strings.Contains(out, `"p"`) || strings.Contains(out, "Bearer tk") || strings.Contains(out, `"new"`) {
+		t.Errorf("log not redacted: %s", out)
+	}
+	if strings.Contains(out, "secret-token") || !strings.Contains(out, "/login?a=1&token=%2A%2A%2A") {
+		t.Errorf("query not redacted: %s", out)
+	}
+	if !strings.Contains(out, `"password":"***"`) || !strings.Contains(out, `"name":"a"`) {
+		t.Errorf("unexpected log: %s", out)
+	}
+	if len(DefaultClient.middlewares) != 0 {
+		t.Error("Use should not modify the original client")
+	}
+}
+
+func TestRequestIDRetry(t *testing.T) {
+	var ids []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Request-Id"))
+		if len(ids) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client := DefaultClient.Use(RequestID(""))
+	resp, err := client.NewRequest().Url(srv.URL).
+		Retry(RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, Logger: &Logger{InfoLogger: log.New(io.Discard, "", 0)}}).
+		GetCtx(context.Background())
+	if err != nil || resp.Attempts != 3 {
+		t.Fatalf("resp=%+v err=%v", resp, err)
+	}
+	if len(ids[0]) != 32 || ids[1] != ids[0] || ids[2] != ids[0] {
+		t.Errorf("retries should share one request id, got %v", ids)
+	}
+	// 已设置的ID保持不变
+	ids = nil
+	if _, err = client.NewRequest().Url(srv.URL).AddHeader("X-Request-Id", "abc").GetCtx(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != "abc" {
+		t.Errorf("existing request id replaced: %v", ids)
+	}
+}
+
+func TestMiddlewaresCrossHostRedirect(t *testing.T) {
+	var leaked http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = r.Header.Clone()
+	}))
+	defer other.Close()
+	var sameHostAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/start" {
+			http.Redirect(w, r, "/next", http.StatusFound)
+			return
+		}
+		sameHostAuth = r.Header.Get("Authorization")
+		http.Redirect(w, r, other.URL+"/landing", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	client := DefaultClient.Use(
+		BearerAuth("secret"),
+		SetHeaders(map[string]string{"X-Tenant": "t1"}),
+		HMACSign(SignOptions{KeyID: "app1", Secret: []byte("s")}),
+	)
+	resp, err := client.NewRequest().Url(srv.URL + "/start").Use(BasicAuth("u", "p")).GetCtx(context.Background())
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("resp=%v err=%v", resp, err)
+	}
+	// 同一主机的重定向保留凭证
+	if !strings.HasPrefix(sameHostAuth, "Basic ") {
+		t.Errorf("same host redirect lost credentials: %q", sameHostAuth)
+	}
+	for _, key := range []string{"Authorization", "X-Tenant", "X-Signature", "X-Key-Id"} {
+		if leaked.Get(key) != "" {
+			t.Errorf("%s sent to another host: %q", key, leaked.Get(key))
+		}
+	}
+}
@@ -138,6 +138,7 @@ func (p *Upgrade) updateAvailable() bool {
 	err, body := NewRequest().
 		Url(p.CheckUrl).
 		Timeout(10).
+		Retry(RetryPolicy{MaxAttempts: 3, Deadline: time.Minute}).
 		AddParam("id", p.ID).
 		AddHeader("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36").
 		Get()